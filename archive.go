@@ -0,0 +1,176 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ExtractArchiveData 从 zip 或 tar.gz 压缩包中解压 data 目录到 ca.dataDir，用于无法 git clone 的环境
+// 压缩包中的 data 目录可以位于任意前缀下（如 domain-list-community-master/data），取最浅的一个
+func (ca *CategoryAnalyzer) ExtractArchiveData(archive string) error {
+	tmpDir, err := os.MkdirTemp("", "domain-list-community-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	switch {
+	case strings.HasSuffix(archive, ".zip"):
+		err = extractZip(archive, tmpDir)
+	case strings.HasSuffix(archive, ".tar.gz"), strings.HasSuffix(archive, ".tgz"):
+		err = extractTarGz(archive, tmpDir)
+	default:
+		return fmt.Errorf("不支持的压缩包格式: %s（仅支持 .zip 和 .tar.gz）", archive)
+	}
+	if err != nil {
+		return fmt.Errorf("解压 %s 失败: %w", archive, err)
+	}
+
+	dataRoot, err := findDataDir(tmpDir)
+	if err != nil {
+		return err
+	}
+	if dataRoot == "" {
+		return fmt.Errorf("压缩包 %s 中没有 data 目录", archive)
+	}
+
+	if err := os.RemoveAll(ca.dataDir); err != nil {
+		return err
+	}
+	if err := copyDir(dataRoot, ca.dataDir); err != nil {
+		os.RemoveAll(ca.dataDir)
+		return fmt.Errorf("复制数据目录失败: %w", err)
+	}
+	return nil
+}
+
+// findDataDir 返回 root 下最浅的名为 data 的目录，不存在时返回空字符串
+func findDataDir(root string) (string, error) {
+	found := ""
+	foundDepth := 0
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() || d.Name() != "data" {
+			return nil
+		}
+		depth := strings.Count(p, string(filepath.Separator))
+		if found == "" || depth < foundDepth {
+			found, foundDepth = p, depth
+		}
+		return filepath.SkipDir
+	})
+	return found, err
+}
+
+// archivePath 将压缩包内的条目名转换为 dir 下的路径，拒绝绝对路径和跳出 dir 的条目
+func archivePath(dir, name string) (string, error) {
+	cleaned := path.Clean(strings.ReplaceAll(name, `\`, "/"))
+	if path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("非法的条目路径: %s", name)
+	}
+	return filepath.Join(dir, filepath.FromSlash(cleaned)), nil
+}
+
+// writeArchiveFile 将条目内容写入 target，必要时创建上级目录
+func writeArchiveFile(target string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		return err
+	}
+	file, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// extractZip 将 zip 中的目录和普通文件解压到 dir
+func extractZip(archive, dir string) error {
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	for _, f := range reader.File {
+		target, err := archivePath(dir, f.Name)
+		if err != nil {
+			return err
+		}
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, os.ModePerm); err != nil {
+				return err
+			}
+			continue
+		}
+		if !f.Mode().IsRegular() {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = writeArchiveFile(target, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractTarGz 将 tar.gz 中的目录和普通文件解压到 dir，链接等其他条目被忽略
+func extractTarGz(archive, dir string) error {
+	file, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target, err := archivePath(dir, header.Name)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, os.ModePerm); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeArchiveFile(target, tr); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MissingInclude 表示一条无法解析的include
+type MissingInclude struct {
+	Source string // 声明include的分类
+	Target string // 找不到的目标分类
+}
+
+// MissingIncludes 返回所有无法解析的include，按来源分类和目标排序
+func (ca *CategoryAnalyzer) MissingIncludes() []MissingInclude {
+	result := append([]MissingInclude(nil), ca.missingIncludes...)
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Source != result[j].Source {
+			return result[i].Source < result[j].Source
+		}
+		return result[i].Target < result[j].Target
+	})
+	return result
+}
+
+// FixSuggestion 表示一个无法解析的include目标及其可能的正确名称
+type FixSuggestion struct {
+	Target     string   // 无法解析的目标
+	Suggestion string   // 最接近的已知分类，为空表示没有合适的候选
+	Sources    []string // 引用该目标的分类
+}
+
+// SuggestFixes 按目标分组无法解析的include，并用编辑距离匹配最接近的已知分类
+func (ca *CategoryAnalyzer) SuggestFixes() []FixSuggestion {
+	sources := make(map[string][]string)
+	for _, missing := range ca.missingIncludes {
+		sources[missing.Target] = append(sources[missing.Target], missing.Source)
+	}
+
+	var names []string
+	for name := range ca.categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var suggestions []FixSuggestion
+	for target, from := range sources {
+		sort.Strings(from)
+		suggestions = append(suggestions, FixSuggestion{
+			Target:     target,
+			Suggestion: closestName(target, names),
+			Sources:    from,
+		})
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		return suggestions[i].Target < suggestions[j].Target
+	})
+
+	return suggestions
+}
+
+// PrintFixSuggestions 打印无法解析的include及修复建议
+func (ca *CategoryAnalyzer) PrintFixSuggestions() {
+	suggestions := ca.SuggestFixes()
+	if len(suggestions) == 0 {
+		fmt.Println("✅ 所有 include 均已解析")
+		return
+	}
+
+	fmt.Printf("🔧 发现 %d 个无法解析的 include 目标:\n", len(suggestions))
+	for _, s := range suggestions {
+		if s.Suggestion != "" {
+			fmt.Printf("   %s → did you mean %s?", s.Target, s.Suggestion)
+		} else {
+			fmt.Printf("   %s → 没有相近的分类", s.Target)
+		}
+		fmt.Printf("  (来自: %s)\n", strings.Join(s.Sources, ", "))
+	}
+}
+
+// closestName 返回与 target 编辑距离最小的候选名称，距离过大时返回空字符串
+func closestName(target string, candidates []string) string {
+	// 允许的最大距离随名称长度增长，避免为短名称给出离谱的建议
+	maxDistance := len([]rune(target)) / 3
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+
+	best := ""
+	bestDistance := maxDistance + 1
+	for _, candidate := range candidates {
+		if d := levenshtein(target, candidate); d < bestDistance {
+			best = candidate
+			bestDistance = d
+		}
+	}
+	return best
+}
+
+// levenshtein 计算两个字符串的编辑距离
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+// PurityViolation 表示一个包含直接规则的 category-* 文件
+type PurityViolation struct {
+	Category string
+	Lines    []int // 直接规则所在行号
+}
+
+// CheckCategoryPurity 检查 category-* 文件是否只通过 include 聚合，不直接声明规则
+func (ca *CategoryAnalyzer) CheckCategoryPurity() []PurityViolation {
+	var violations []PurityViolation
+	for name, node := range ca.categories {
+		if !strings.HasPrefix(name, "category-") {
+			continue
+		}
+
+		if node.RuleCount > 0 {
+			violations = append(violations, PurityViolation{Category: name, Lines: node.RuleLines})
+		}
+	}
+	sort.Slice(violations, func(i, j int) bool {
+		return violations[i].Category < violations[j].Category
+	})
+
+	return violations
+}
+
+// PrintCategoryPurity 打印 category-* 文件的纯度检查结果
+func (ca *CategoryAnalyzer) PrintCategoryPurity() {
+	violations := ca.CheckCategoryPurity()
+	if len(violations) == 0 {
+		fmt.Println("✅ 所有 category-* 文件都只包含 include")
+		return
+	}
+
+	fmt.Printf("🧹 %d 个 category-* 文件包含直接规则:\n", len(violations))
+	for _, v := range violations {
+		lines := make([]string, len(v.Lines))
+		for i, line := range v.Lines {
+			lines[i] = strconv.Itoa(line)
+		}
+		fmt.Printf("   %s: 第 %s 行\n", v.Category, strings.Join(lines, ", "))
+	}
+}
+
+// SelfInclude 表示一条 include 自身的声明
+type SelfInclude struct {
+	Category string
+	Line     int
+}
+
+// SelfIncludes 返回所有包含自身的声明，按分类名和行号排序
+func (ca *CategoryAnalyzer) SelfIncludes() []SelfInclude {
+	result := append([]SelfInclude(nil), ca.selfIncludes...)
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Category != result[j].Category {
+			return result[i].Category < result[j].Category
+		}
+		return result[i].Line < result[j].Line
+	})
+	return result
+}
+
+// PrintSelfIncludes 打印包含自身的文件
+func (ca *CategoryAnalyzer) PrintSelfIncludes() {
+	selfIncludes := ca.SelfIncludes()
+	if len(selfIncludes) == 0 {
+		fmt.Println("✅ 没有文件包含自身")
+		return
+	}
+
+	fmt.Printf("🔁 %d 处 include 指向文件自身:\n", len(selfIncludes))
+	for _, s := range selfIncludes {
+		fmt.Printf("   %s: 第 %d 行\n", s.Category, s.Line)
+	}
+}
+
+// Cycles 返回检测到的循环 include，每个循环首尾为同一个分类
+func (ca *CategoryAnalyzer) Cycles() [][]string {
+	result := make([][]string, len(ca.cycles))
+	for i, cycle := range ca.cycles {
+		result[i] = append([]string(nil), cycle...)
+	}
+	return result
+}
+
+// RegexpError 表示一条无法编译的 regexp 规则
+type RegexpError struct {
+	File string
+	Line int
+	Err  error
+}
+
+// ValidateRegexps 编译所有文件中的 regexp 规则，返回失败的规则，按文件和行号排序
+func (ca *CategoryAnalyzer) ValidateRegexps() []RegexpError {
+	var names []string
+	for name := range ca.categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []RegexpError
+	for _, name := range names {
+		file := filepath.Join(ca.dataDir, name)
+		rules, err := ca.parseRules(file)
+		if err != nil {
+			continue
+		}
+		for _, rule := range rules {
+			if rule.Type != "regexp" {
+				continue
+			}
+			if _, err := regexp.Compile(rule.Value); err != nil {
+				errs = append(errs, RegexpError{File: file, Line: rule.Line, Err: err})
+			}
+		}
+	}
+	return errs
+}
+
+// DuplicateRules 返回在多个分类中直接声明的规则，键为 类型:值，值为声明它的分类（排序）
+// domain、full、keyword 的值不区分大小写，regexp 保持原样
+func (ca *CategoryAnalyzer) DuplicateRules() map[string][]string {
+	var names []string
+	for name := range ca.categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	declared := make(map[string][]string)
+	for _, name := range names {
+		if ca.skippedFiles[name] {
+			continue
+		}
+		rules, err := ca.parseRules(filepath.Join(ca.dataDir, name))
+		if err != nil {
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, rule := range rules {
+			if rule.Type == "include" {
+				continue
+			}
+			value := rule.Value
+			if rule.Type != "regexp" {
+				value = strings.ToLower(value)
+			}
+			key := rule.Type + ":" + value
+			if !seen[key] {
+				seen[key] = true
+				declared[key] = append(declared[key], name)
+			}
+		}
+	}
+
+	dupes := make(map[string][]string)
+	for key, categories := range declared {
+		if len(categories) > 1 {
+			dupes[key] = categories
+		}
+	}
+	return dupes
+}
+
+// PrintDuplicateRules 打印在多个分类中重复声明的规则
+func (ca *CategoryAnalyzer) PrintDuplicateRules() {
+	dupes := ca.DuplicateRules()
+	if len(dupes) == 0 {
+		fmt.Println("✅ 没有在多个分类中重复声明的规则")
+		return
+	}
+
+	keys := make([]string, 0, len(dupes))
+	for key := range dupes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Printf("♻️  %d 条规则在多个分类中重复声明:\n", len(keys))
+	for _, key := range keys {
+		fmt.Printf("   %s: %s\n", key, strings.Join(dupes[key], ", "))
+	}
+}
+
+// PrintRegexpErrors 打印无法编译的 regexp 规则，返回是否全部有效
+func (ca *CategoryAnalyzer) PrintRegexpErrors() bool {
+	errs := ca.ValidateRegexps()
+	if len(errs) == 0 {
+		fmt.Println("✅ 所有 regexp 规则都可以编译")
+		return true
+	}
+
+	fmt.Printf("❌ %d 条 regexp 规则无法编译:\n", len(errs))
+	for _, e := range errs {
+		fmt.Printf("   %s:%d: %v\n", e.File, e.Line, e.Err)
+	}
+	return false
+}
+
+// VerifyTree 检查父子指针是否一致，返回发现的问题描述
+// 每个子节点的 Parents 必须包含父节点，每个 Parents 中的节点也必须把它列为子节点
+func (ca *CategoryAnalyzer) VerifyTree() []string {
+	var names []string
+	for name := range ca.categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems []string
+	for _, name := range names {
+		node := ca.categories[name]
+		if node.Name != name {
+			problems = append(problems, fmt.Sprintf("%s: 节点名称为 %s", name, node.Name))
+		}
+		for _, childName := range sortedChildNames(node) {
+			child := node.Children[childName]
+			if child.Name != childName {
+				problems = append(problems, fmt.Sprintf("%s: 子节点键 %s 指向 %s", name, childName, child.Name))
+			}
+			if ca.categories[child.Name] != child {
+				problems = append(problems, fmt.Sprintf("%s: 子节点 %s 不在分类表中", name, childName))
+			}
+			if !containsNode(child.Parents, node) {
+				problems = append(problems, fmt.Sprintf("%s → %s: 子节点的 Parents 中没有父节点", name, childName))
+			}
+		}
+		for _, parent := range node.Parents {
+			if parent.Children[name] != node {
+				problems = append(problems, fmt.Sprintf("%s ← %s: 父节点没有把它列为子节点", name, parent.Name))
+			}
+		}
+	}
+	for _, childName := range sortedChildNames(ca.tree) {
+		if child := ca.tree.Children[childName]; len(child.Parents) > 0 {
+			problems = append(problems, fmt.Sprintf("%s: 顶级分类存在父节点 %s", childName, child.Parents[0].Name))
+		}
+	}
+	return problems
+}
+
+// containsNode 判断节点列表中是否包含 target
+func containsNode(nodes []*TreeNode, target *TreeNode) bool {
+	for _, n := range nodes {
+		if n == target {
+			return true
+		}
+	}
+	return false
+}
+
+// PrintTreeVerification 打印父子指针检查结果，返回是否一致
+func (ca *CategoryAnalyzer) PrintTreeVerification() bool {
+	problems := ca.VerifyTree()
+	if len(problems) == 0 {
+		fmt.Println("✅ 父子指针一致")
+		return true
+	}
+
+	fmt.Printf("❌ %d 处父子指针不一致:\n", len(problems))
+	for _, problem := range problems {
+		fmt.Printf("   %s\n", problem)
+	}
+	return false
+}
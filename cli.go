@@ -0,0 +1,741 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// command 子命令
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
+
+// commands 返回所有子命令，第一个为默认子命令
+func commands() []command {
+	return []command{
+		{"generate", "生成控制台树、JSON 和 HTML 等输出（默认）", runGenerate},
+		{"check", "检查数据文件中的常见问题", runCheck},
+		{"diff", "对比两个快照或数据目录", runDiff},
+		{"query", "查询树结构信息", runQuery},
+		{"serve", "通过 HTTP 提供交互式页面", runServe},
+		{"version", "打印版本和构建提交", runVersion},
+	}
+}
+
+// runCLI 根据第一个参数分发子命令，没有子命令时执行 generate
+func runCLI(args []string) int {
+	name := "generate"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	} else if len(args) > 0 && (args[0] == "-version" || args[0] == "--version") {
+		name, args = "version", args[1:]
+	}
+
+	for _, cmd := range commands() {
+		if cmd.name == name {
+			return cmd.run(args)
+		}
+	}
+
+	if name == "help" {
+		printCommands()
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "未知的子命令: %s\n\n", name)
+	printCommands()
+	return 2
+}
+
+// runVersion 打印版本信息
+func runVersion(args []string) int {
+	fs := newFlagSet("version", "")
+	fs.Parse(args)
+	fmt.Printf("%s %s\n", filepath.Base(os.Args[0]), versionString())
+	return 0
+}
+
+// printCommands 打印子命令列表
+func printCommands() {
+	fmt.Fprintf(os.Stderr, "用法: %s [子命令] [参数]\n\n子命令:\n", filepath.Base(os.Args[0]))
+	for _, cmd := range commands() {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\n使用 \"<子命令> -h\" 查看各子命令的参数\n")
+}
+
+// newFlagSet 创建子命令的参数集，usage 描述位置参数
+func newFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: %s %s %s\n\n", filepath.Base(os.Args[0]), name, usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// commonOptions 各子命令共用的解析参数
+type commonOptions struct {
+	dataDir       string
+	noDownload    bool
+	repoURL       string
+	branch        string
+	useGoGit      bool
+	proxy         string
+	retries       int
+	maxAge        time.Duration
+	aliasesFile   string
+	archive       string
+	classifyFile  string
+	profile       bool
+	quiet         bool
+	buildDepth    int
+	maxFileSize   int64
+	commentStyles string
+	deadline      time.Duration
+}
+
+// register 注册公共参数
+func (o *commonOptions) register(fs *flag.FlagSet) {
+	fs.BoolVar(&o.profile, "profile", false, "结束时打印各阶段耗时")
+	fs.IntVar(&o.buildDepth, "build-depth", 0, "构建树时保留的最大 include 深度（顶级为 1），超出部分不构建，0 表示不限制")
+	fs.BoolVar(&o.quiet, "quiet", false, "不输出进度、状态和警告信息（错误仍会输出）")
+	fs.Int64Var(&o.maxFileSize, "max-file-size", defaultMaxFileSize, "单个数据文件的大小上限（字节），超过则跳过解析，0 表示不限制")
+	fs.StringVar(&o.commentStyles, "comment-styles", strings.Join(defaultCommentMarkers, ","), "以逗号分隔的注释标记")
+	fs.DurationVar(&o.deadline, "deadline", 0, "整个运行的最长时间（如 30s、5m），超时后中止并以非零状态退出，0 表示不限制")
+}
+
+// registerData 注册数据目录参数，diff 通过位置参数指定目录，不使用它们
+func (o *commonOptions) registerData(fs *flag.FlagSet) {
+	fs.StringVar(&o.dataDir, "data", "./data", "domain-list-community 的 data 目录")
+	fs.BoolVar(&o.noDownload, "no-download", false, "数据目录不存在时直接报错，不自动克隆仓库")
+	fs.StringVar(&o.repoURL, "repo", v2rayRepoURL, "自动下载数据时克隆的仓库地址")
+	fs.StringVar(&o.branch, "branch", "", "自动下载数据时克隆的分支，为空时使用默认分支")
+	fs.StringVar(&o.archive, "archive", "", "从 .zip 或 .tar.gz 压缩包解压 data 目录（覆盖 -data 目录，不使用 git）")
+	fs.StringVar(&o.aliasesFile, "aliases", "", "分类别名文件（每行 旧名=新名），无法解析的 include 会按别名再查找一次")
+	fs.StringVar(&o.classifyFile, "classify", "", "节点分类配置 JSON（{\"companies\":[...],\"countries\":[...]}），缺省使用内置列表")
+	fs.DurationVar(&o.maxAge, "max-age", 7*24*time.Hour, "自动下载的数据超过该时长后重新下载，0 表示不过期")
+	fs.StringVar(&o.proxy, "proxy", "", "自动下载数据时使用的 HTTP(S) 代理，优先于 HTTPS_PROXY/HTTP_PROXY 环境变量")
+	fs.IntVar(&o.retries, "retries", defaultRetries, "自动下载数据时克隆的最多尝试次数，失败后以指数退避重试")
+	fs.BoolVar(&o.useGoGit, "use-go-git", false, "自动下载数据时使用内置的 go-git 克隆，不依赖 git 命令（失败时回退到 git 命令）")
+}
+
+// parse 解析参数并立即应用 -quiet，之后的所有进度输出都受它控制
+func (o *commonOptions) parse(fs *flag.FlagSet, args []string) {
+	fs.Parse(args)
+	if o.quiet {
+		logger.SetOutput(io.Discard)
+	}
+}
+
+// configure 将公共参数应用到分析器
+func (o *commonOptions) configure(ca *CategoryAnalyzer) {
+	ca.maxFileSize = o.maxFileSize
+	ca.useGoGit = o.useGoGit
+	ca.buildDepth = o.buildDepth
+	ca.proxy = o.proxy
+	ca.retries = o.retries
+	ca.commentMarkers = nil
+	for _, marker := range strings.Split(o.commentStyles, ",") {
+		if marker = strings.TrimSpace(marker); marker != "" {
+			ca.commentMarkers = append(ca.commentMarkers, marker)
+		}
+	}
+}
+
+// context 返回受 -deadline 约束的 context
+func (o *commonOptions) context() (context.Context, context.CancelFunc) {
+	if o.deadline > 0 {
+		return context.WithTimeout(context.Background(), o.deadline)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// checkDeadline 在阶段之间检查是否已超时，超时时返回 context.DeadlineExceeded
+func (o *commonOptions) checkDeadline(ctx context.Context) error {
+	if err := ctx.Err(); errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return nil
+}
+
+// failure 打印错误并返回退出状态 1，超时导致的失败打印 -deadline 提示，其他错误加上 prefix
+func (o *commonOptions) failure(prefix string, err error) int {
+	if errors.Is(err, context.DeadlineExceeded) {
+		fmt.Fprintf(os.Stderr, "⏰ 运行超过 --deadline 限制 (%v)，已中止\n", o.deadline)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s%v\n", prefix, err)
+	}
+	return 1
+}
+
+// ensureData 数据目录不存在时克隆仓库获取数据，-no-download 时直接返回错误
+// 自动下载的数据超过 -max-age 后重新下载，手动准备的数据目录不会被覆盖
+// 指定 -archive 时总是从压缩包解压，不再克隆
+func (o *commonOptions) ensureData(ctx context.Context, ca *CategoryAnalyzer) error {
+	if o.archive != "" {
+		logger.Printf("📦 正在从 %s 解压数据到 %s...\n", o.archive, ca.dataDir)
+		return ca.ExtractArchiveData(o.archive)
+	}
+
+	_, err := os.Stat(ca.dataDir)
+	if err == nil {
+		downloaded, ok := ca.dataCacheTime()
+		if !ok || o.noDownload || o.maxAge <= 0 || time.Since(downloaded) < o.maxAge {
+			return nil
+		}
+
+		logger.Printf("📥 数据已下载 %v，超过 -max-age (%v)，正在从 %s 更新...\n",
+			time.Since(downloaded).Round(time.Minute), o.maxAge, o.repoURL)
+		if err := ca.DownloadV2RayRepoData(ctx, o.repoURL, o.branch); err != nil {
+			if _, statErr := os.Stat(ca.dataDir); statErr != nil {
+				return err
+			}
+			logger.Printf("⚠️  %v，继续使用已有数据\n", err)
+		}
+		return nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if o.noDownload {
+		return fmt.Errorf("数据目录不存在: %s（已禁用自动下载）", ca.dataDir)
+	}
+
+	logger.Printf("📥 数据目录 %s 不存在，正在从 %s 下载...\n", ca.dataDir, o.repoURL)
+	return ca.DownloadV2RayRepoData(ctx, o.repoURL, o.branch)
+}
+
+// load 扫描数据目录并构建树，数据缺失时先自动下载
+func (o *commonOptions) load(ctx context.Context, ca *CategoryAnalyzer, profiler *phaseProfiler) error {
+	start := time.Now()
+	if err := o.ensureData(ctx, ca); err != nil {
+		return err
+	}
+	profiler.track("download", start)
+
+	if o.aliasesFile != "" {
+		aliases, err := loadAliases(o.aliasesFile)
+		if err != nil {
+			return err
+		}
+		ca.aliases = aliases
+	}
+	if o.classifyFile != "" {
+		classify, err := loadClassifyConfig(o.classifyFile)
+		if err != nil {
+			return err
+		}
+		ca.classify = classify
+	}
+
+	start = time.Now()
+	if err := ca.ScanDataDirectory(ctx); err != nil {
+		return err
+	}
+	profiler.track("scan", start)
+
+	start = time.Now()
+	if err := ca.BuildTree(ctx); err != nil {
+		return err
+	}
+	profiler.track("build", start)
+
+	return nil
+}
+
+// optionalExport 通过参数开启的附加导出
+type optionalExport struct {
+	phase string
+	label string
+	file  *string
+	run   func(filename string) error
+}
+
+// runGenerate 生成控制台树和各种格式的导出文件
+func runGenerate(args []string) int {
+	var common commonOptions
+	fs := newFlagSet("generate", "[参数]")
+	common.register(fs)
+	common.registerData(fs)
+
+	rootName := fs.String("root-name", defaultRootName, "虚拟根节点的名称")
+	provenance := fs.Bool("provenance", false, "在 JSON 中为每个节点记录源文件路径和 include 所在行号")
+	var viewRange depthRange
+	fs.Var(&viewRange, "depth-range", "只导出深度在 MIN-MAX 之间的节点（顶级为 1），保留置灰的祖先作为上下文")
+	filter := fs.String("filter", "", "只保留名称匹配的节点及其祖先（子串或通配符，如 google、geolocation-*）")
+	fileMode := fs.String("file-mode", "0644", "导出文件的权限（八进制）")
+	renderDepth := fs.Int("depth", 0, "控制台和HTML只展示到第 N 层（顶级为 1），更深的子树显示为 … (+隐藏数量)，0 表示不限制")
+	noColor := fs.Bool("no-color", false, "控制台树不着色（输出不是终端或设置了 NO_COLOR 时自动关闭）")
+	showCounts := fs.Bool("counts", false, "控制台树中显示每个节点（含后代）的规则总数")
+	jsonFile := fs.String("json", "domain_tree.json", "JSON 输出文件，- 表示写到标准输出")
+	toStdout := fs.Bool("stdout", false, "将 JSON 写到标准输出（等同于 -json -），其他输出改写到标准错误")
+	jsonLegacy := fs.Bool("json-legacy", false, "JSON 使用旧格式（仅 name 和 children 映射）")
+	htmlFile := fs.String("html", "domain_tree.html", "HTML 输出文件")
+
+	// HTML 选项
+	sourceBase := fs.String("source-base", defaultSourceBase, "源码链接的 raw 地址前缀，其后拼接 /<分支或提交>/data/<分类名>")
+	pinCommit := fs.String("pin-commit", "", "源码链接固定到指定提交 SHA；为 auto 时使用自动下载数据时记录的提交")
+	templateFile := fs.String("template", "", "使用自定义 HTML 模板文件")
+	templateFallback := fs.Bool("template-fallback", false, "自定义模板无效时回退到内置模板（打印警告）")
+	title := fs.String("title", defaultHTMLTitle, "HTML 页面标题")
+	tz := fs.String("tz", defaultTimezone, "HTML 更新时间使用的 IANA 时区名称")
+	richHTML := fs.Bool("rich-html", false, "额外启用类型筛选和深链接（搜索框、深色模式和统计面板默认开启）")
+	noJS := fs.Bool("no-js", false, "生成不依赖 JavaScript 的 HTML（使用 <details> 折叠）")
+	strict := fs.Bool("strict", false, "存在无法解析的 include 时以非零状态退出")
+	subtreeRoot := fs.String("root", "", "以指定分类为根，所有导出只包含该分类及其后代")
+	watch := fs.Bool("watch", false, "生成后继续监听数据目录，文件变化时用相同参数重新生成")
+	scanOnly := fs.Bool("scan-only", false, "只扫描数据并打印控制台树和统计，不写任何文件")
+	sourceLines := fs.Bool("source-lines", false, "在 HTML 中内嵌每个分类的源文件内容，离线也能查看（会增大内存和文件体积）")
+	var excludes patternList
+	fs.Var(&excludes, "exclude", "删除名称匹配该模式（filepath.Match）的分类及只被它们 include 的后代，可重复指定")
+
+	// 附加导出
+	leavesFile := fs.String("leaves", "", "将所有叶子节点导出为CSV (name,class,depth,path)")
+	textFile := fs.String("text", "", "将控制台树形结构（不着色）导出到指定文本文件")
+	listFile := fs.String("list", "", "导出按名称排序的分类名列表（每行一个）")
+	csvFile := fs.String("csv", "", "将所有分类导出为CSV (name,parent,depth,child_count)")
+	cytoscapeFile := fs.String("cytoscape", "", "导出 Cytoscape.js elements JSON 到指定文件")
+	markdownFile := fs.String("markdown", "", "导出 Markdown 嵌套列表到指定文件")
+	geositeDir := fs.String("geosite", "", "为每个顶级分类导出展开 include 后的规则文件到指定目录")
+	singBoxCategory := fs.String("singbox-category", "", "导出 sing-box 规则集的分类")
+	singBoxFile := fs.String("singbox", "", "将 -singbox-category 指定的分类导出为 sing-box 规则集 JSON")
+	dotFile := fs.String("dot", "", "导出 Graphviz DOT 到指定文件")
+	graphMLFile := fs.String("graphml", "", "导出 GraphML 到指定文件")
+	svgFile := fs.String("svg", "", "将树直接绘制为 SVG 图片（不依赖 Graphviz）")
+	mermaidFile := fs.String("mermaid", "", "导出 Mermaid 流程图到指定文件")
+	mermaidDepth := fs.Int("mermaid-depth", 0, "Mermaid 流程图保留的最大深度（顶级为 1），0 表示不限制")
+	tomlFile := fs.String("toml", "", "以边表形式导出 TOML 到指定文件")
+	adjacencyFile := fs.String("adjacency", "", "导出文件中原始的 include 邻接表 JSON（含无法解析的目标）到指定文件")
+	jsonlFile := fs.String("jsonl", "", "按先序遍历导出 JSON Lines（每行一个节点）到指定文件")
+	yamlFile := fs.String("yaml", "", "导出与 JSON 相同结构的 YAML 到指定文件")
+	samplesFile := fs.String("samples", "", "为每个叶子分类导出域名样例 JSON 到指定文件")
+	samplesK := fs.Int("samples-k", 5, "每个叶子分类的样例数量")
+	common.parse(fs, args)
+
+	if *watch {
+		if common.archive != "" {
+			fmt.Fprintln(os.Stderr, "❌ -watch 不能与 -archive 同时使用：每次生成都会重新解压并替换数据目录")
+			return 2
+		}
+		return runWatch(common.dataDir, withoutFlag(args, "watch"))
+	}
+
+	mode, err := strconv.ParseUint(*fileMode, 8, 32)
+	if err != nil || mode > 0777 {
+		fmt.Fprintf(os.Stderr, "❌ 无效的 --file-mode: %s\n", *fileMode)
+		return 2
+	}
+	outputFileMode = os.FileMode(mode)
+	if *toStdout {
+		*jsonFile = "-"
+	}
+	if *jsonFile == "-" {
+		// 控制台树等原本写到标准输出的内容改写到标准错误，标准输出只留给 JSON
+		os.Stdout = os.Stderr
+		defer func() { os.Stdout = realStdout }()
+	}
+	if *singBoxFile != "" && *singBoxCategory == "" {
+		fmt.Fprintln(os.Stderr, "❌ 使用 -singbox 时需要通过 -singbox-category 指定分类")
+		return 2
+	}
+	if *samplesK <= 0 {
+		fmt.Fprintf(os.Stderr, "❌ 无效的 -samples-k: %d（必须大于 0）\n", *samplesK)
+		return 2
+	}
+
+	profiler := &phaseProfiler{enabled: common.profile}
+	defer profiler.Print()
+	ctx, cancel := common.context()
+	defer cancel()
+
+	logger.Println("🌳 Domain List Community 多格式可视化工具")
+	logger.Println(strings.Repeat("=", 50))
+
+	analyzer := NewCategoryAnalyzer(common.dataDir)
+	common.configure(analyzer)
+	analyzer.tree.Name = *rootName
+	analyzer.provenance = *provenance
+	analyzer.consoleCounts = *showCounts
+	analyzer.renderDepth = *renderDepth
+	analyzer.jsonLegacy = *jsonLegacy
+	analyzer.keepSourceLines = *sourceLines
+	analyzer.consoleColor = !*noColor && os.Getenv("NO_COLOR") == "" && stdoutIsTerminal()
+	if err := checkSourceBase(*sourceBase); err != nil {
+		logger.Printf("⚠️  %v，源码链接可能无法打开\n", err)
+	}
+	analyzer.sourceBase = *sourceBase
+	if *pinCommit != "" && *pinCommit != "auto" {
+		analyzer.sourceRef = *pinCommit
+	}
+	if *richHTML {
+		analyzer.html = richHTMLOptions()
+	}
+	analyzer.html.NoJS = *noJS
+	analyzer.html.Title = *title
+	analyzer.location = loadTimezone(*tz)
+	if *templateFile != "" {
+		text, err := loadHTMLTemplate(*templateFile)
+		switch {
+		case err == nil:
+			analyzer.htmlTemplateName = filepath.Base(*templateFile)
+			analyzer.htmlTemplate = text
+		case *templateFallback:
+			logger.Printf("⚠️  %v，改用内置模板\n", err)
+		default:
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+	}
+
+	if err := common.load(ctx, analyzer, profiler); err != nil {
+		return common.failure("错误: ", err)
+	}
+	// auto 需要下载时记录的提交，只能在数据准备好之后读取
+	if *pinCommit == "auto" {
+		if sha, err := analyzer.dataCacheCommit(); err != nil {
+			logger.Printf("⚠️  %v，源码链接继续使用 %s\n", err, defaultSourceRef)
+		} else {
+			analyzer.sourceRef = sha
+		}
+	}
+	if missing := analyzer.MissingIncludes(); *strict && len(missing) > 0 {
+		fmt.Fprintf(os.Stderr, "❌ --strict: 发现 %d 条无法解析的 include\n", len(missing))
+		return 1
+	}
+
+	if len(excludes) > 0 {
+		removed := analyzer.Exclude(excludes)
+		logger.Printf("✂️  -exclude %s: 删除了 %d 个分类\n", excludes.String(), removed)
+	}
+	if *subtreeRoot != "" {
+		if err := analyzer.RootAt(*subtreeRoot); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+	}
+	if viewRange.Max > 0 {
+		analyzer.tree = analyzer.DepthRangeView(viewRange)
+	}
+	if *filter != "" {
+		analyzer.tree = analyzer.Search(*filter)
+	}
+
+	// 1. 控制台输出
+	start := time.Now()
+	analyzer.PrintConsoleTree()
+	analyzer.PrintStats()
+	profiler.track("console", start)
+
+	if *scanOnly {
+		logger.Println("\n🔍 -scan-only: 已跳过所有文件导出")
+		return 0
+	}
+
+	logger.Println("\n" + strings.Repeat("=", 50))
+	logger.Println("📤 正在生成多种格式的输出文件...")
+
+	// 2. JSON格式
+	if err := common.checkDeadline(ctx); err != nil {
+		return common.failure("", err)
+	}
+	start = time.Now()
+	if err := analyzer.ExportJSON(*jsonFile); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ JSON导出失败: %v\n", err)
+	}
+	profiler.track("export-json", start)
+
+	// 3. 交互式HTML
+	if err := common.checkDeadline(ctx); err != nil {
+		return common.failure("", err)
+	}
+	start = time.Now()
+	if err := analyzer.ExportHTML(*htmlFile); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ HTML导出失败: %v\n", err)
+	}
+	profiler.track("export-html", start)
+
+	// 4. 通过参数开启的附加导出
+	exports := []optionalExport{
+		{"export-leaves", "叶子节点", leavesFile, analyzer.ExportLeaves},
+		{"export-text", "文本树", textFile, analyzer.ExportText},
+		{"export-list", "分类列表", listFile, analyzer.ExportList},
+		{"export-csv", "分类CSV", csvFile, analyzer.ExportCSV},
+		{"export-cytoscape", "Cytoscape", cytoscapeFile, analyzer.ExportCytoscape},
+		{"export-markdown", "Markdown", markdownFile, analyzer.ExportMarkdown},
+		{"export-geosite", "geosite 规则", geositeDir, analyzer.ExportGeositeText},
+		{"export-singbox", "sing-box 规则集", singBoxFile, func(filename string) error {
+			return analyzer.ExportSingBox(*singBoxCategory, filename)
+		}},
+		{"export-dot", "DOT", dotFile, analyzer.ExportDOT},
+		{"export-graphml", "GraphML", graphMLFile, analyzer.ExportGraphML},
+		{"export-svg", "SVG", svgFile, analyzer.ExportSVG},
+		{"export-mermaid", "Mermaid", mermaidFile, func(filename string) error {
+			return analyzer.ExportMermaid(filename, *mermaidDepth)
+		}},
+		{"export-toml", "TOML", tomlFile, analyzer.ExportTOML},
+		{"export-yaml", "YAML", yamlFile, analyzer.ExportYAML},
+		{"export-jsonl", "JSON Lines", jsonlFile, analyzer.ExportJSONL},
+		{"export-adjacency", "include 邻接表", adjacencyFile, analyzer.ExportAdjacency},
+		{"export-samples", "域名样例", samplesFile, func(filename string) error {
+			return analyzer.ExportEntrySamples(filename, *samplesK)
+		}},
+	}
+	for _, export := range exports {
+		if *export.file == "" {
+			continue
+		}
+		if err := common.checkDeadline(ctx); err != nil {
+			return common.failure("", err)
+		}
+		start = time.Now()
+		if err := export.run(*export.file); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %s导出失败: %v\n", export.label, err)
+		}
+		profiler.track(export.phase, start)
+	}
+
+	logger.Println("\n✨ 完成！生成的文件:")
+	if *jsonFile == "-" {
+		logger.Println("   📄 标准输出  - JSON数据格式")
+	} else {
+		logger.Printf("   📄 %s  - JSON数据格式\n", *jsonFile)
+	}
+	logger.Printf("   🌐 %s  - 交互式网页\n", *htmlFile)
+
+	return 0
+}
+
+// runCheck 运行数据检查，未指定任何检查时全部运行
+func runCheck(args []string) int {
+	var common commonOptions
+	fs := newFlagSet("check", "[参数]")
+	common.register(fs)
+	common.registerData(fs)
+	suggestFixes := fs.Bool("suggest-fixes", false, "列出无法解析的 include 并给出可能的正确名称")
+	lintCategoryPurity := fs.Bool("lint-category-purity", false, "检查 category-* 文件是否只包含 include 行")
+	lintSelfInclude := fs.Bool("lint-self-include", false, "检查包含自身的文件")
+	validate := fs.Bool("validate", false, "编译所有 regexp 规则，有无效规则时以非零状态退出")
+	verify := fs.Bool("verify", false, "检查树的父子指针是否一致，不一致时以非零状态退出")
+	common.parse(fs, args)
+
+	if !*suggestFixes && !*lintCategoryPurity && !*lintSelfInclude && !*validate && !*verify {
+		*suggestFixes, *lintCategoryPurity, *lintSelfInclude, *validate, *verify = true, true, true, true, true
+	}
+
+	profiler := &phaseProfiler{enabled: common.profile}
+	defer profiler.Print()
+	ctx, cancel := common.context()
+	defer cancel()
+
+	analyzer := NewCategoryAnalyzer(common.dataDir)
+	common.configure(analyzer)
+	if err := common.load(ctx, analyzer, profiler); err != nil {
+		return common.failure("错误: ", err)
+	}
+
+	if *suggestFixes {
+		analyzer.PrintFixSuggestions()
+	}
+	if *lintCategoryPurity {
+		analyzer.PrintCategoryPurity()
+	}
+	if *lintSelfInclude {
+		analyzer.PrintSelfIncludes()
+	}
+	status := 0
+	if *validate && !analyzer.PrintRegexpErrors() {
+		status = 1
+	}
+	if *verify && !analyzer.PrintTreeVerification() {
+		status = 1
+	}
+
+	return status
+}
+
+// runDiff 对比两个 JSON 快照（生成HTML）或两个数据目录（-counts）
+func runDiff(args []string) int {
+	var common commonOptions
+	fs := newFlagSet("diff", "[参数] OLD NEW")
+	common.register(fs)
+	counts := fs.Bool("counts", false, "OLD/NEW 为数据目录，打印各分类规则数量的变化")
+	output := fs.String("o", "", "对比 JSON 快照时额外生成对比 HTML 到指定文件")
+	common.parse(fs, args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+	oldPath, newPath := fs.Arg(0), fs.Arg(1)
+
+	if *counts {
+		ctx, cancel := common.context()
+		defer cancel()
+
+		if err := runDiffCounts(ctx, oldPath, newPath, common.configure); err != nil {
+			return common.failure("❌ 对比失败: ", err)
+		}
+		return 0
+	}
+
+	if err := runSnapshotDiff(oldPath, newPath, *output); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ 对比失败: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runQuery 查询树结构信息
+func runQuery(args []string) int {
+	var common commonOptions
+	fs := newFlagSet("query", "[参数]")
+	common.register(fs)
+	common.registerData(fs)
+	rootDepths := fs.Bool("root-depths", false, "打印每个顶级分类的最大嵌套深度")
+	whoIncludes := fs.String("who-includes", "", "打印 include 了指定分类的所有分类")
+	pathOf := fs.String("path", "", "打印从顶级分类到指定分类的所有 include 路径")
+	report := fs.Bool("report", false, "打印根分类（没有被 include）和叶子分类（不 include 其他分类）")
+	flatten := fs.String("flatten", "", "打印指定分类通过 include 可达的全部具体规则（类型:值，去重排序）")
+	dupes := fs.Bool("dupes", false, "打印在多个分类中直接声明的相同规则")
+	common.parse(fs, args)
+
+	if !*rootDepths && *whoIncludes == "" && !*report && *pathOf == "" && *flatten == "" && !*dupes {
+		fs.Usage()
+		return 2
+	}
+
+	profiler := &phaseProfiler{enabled: common.profile}
+	defer profiler.Print()
+	ctx, cancel := common.context()
+	defer cancel()
+
+	analyzer := NewCategoryAnalyzer(common.dataDir)
+	common.configure(analyzer)
+	if err := common.load(ctx, analyzer, profiler); err != nil {
+		return common.failure("错误: ", err)
+	}
+
+	if *rootDepths {
+		analyzer.PrintTopLevelDepths()
+	}
+	if *whoIncludes != "" {
+		analyzer.PrintDependents(*whoIncludes)
+	}
+	if *report {
+		analyzer.PrintShapeReport()
+	}
+	if *pathOf != "" {
+		analyzer.PrintPaths(*pathOf)
+	}
+	if *dupes {
+		analyzer.PrintDuplicateRules()
+	}
+	if *flatten != "" {
+		rules, err := analyzer.Flatten(*flatten)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		for _, rule := range rules {
+			fmt.Println(rule)
+		}
+	}
+
+	return 0
+}
+
+// runServe 启动 HTTP 服务
+// 默认在启动时加载数据（必要时下载）并生成一次页面，之后的请求直接返回缓存的页面；
+// 指定 -no-download 时数据只来自本地目录，每次请求都重新扫描并生成，以反映文件的修改
+func runServe(args []string) int {
+	var common commonOptions
+	fs := newFlagSet("serve", "[参数]")
+	common.register(fs)
+	common.registerData(fs)
+	addr := fs.String("addr", ":8080", "监听地址")
+	enablePprof := fs.Bool("pprof", false, "注册 /debug/pprof/ 处理器")
+	title := fs.String("title", defaultHTMLTitle, "HTML 页面标题")
+	tz := fs.String("tz", defaultTimezone, "HTML 更新时间使用的 IANA 时区名称")
+	richHTML := fs.Bool("rich-html", false, "额外启用类型筛选和深链接（搜索框、深色模式和统计面板默认开启）")
+	noJS := fs.Bool("no-js", false, "生成不依赖 JavaScript 的 HTML（使用 <details> 折叠）")
+	common.parse(fs, args)
+
+	// 时区只在启动时加载一次，无效时的警告不会在每次请求时重复
+	location := loadTimezone(*tz)
+
+	// render 加载数据并渲染页面，页面先渲染到缓冲区，出错时还能返回 500
+	render := func(ctx context.Context) ([]byte, error) {
+		analyzer := NewCategoryAnalyzer(common.dataDir)
+		common.configure(analyzer)
+		if *richHTML {
+			analyzer.html = richHTMLOptions()
+		}
+		analyzer.html.NoJS = *noJS
+		analyzer.html.Title = *title
+		analyzer.location = location
+
+		if err := common.load(ctx, analyzer, &phaseProfiler{}); err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := analyzer.RenderHTML(&buf); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	// -archive 每次加载都会重新解压，因此也只在启动时加载一次
+	live := common.noDownload && common.archive == ""
+	var cached []byte
+	if !live {
+		ctx, cancel := common.context()
+		page, err := render(ctx)
+		cancel()
+		if err != nil {
+			return common.failure("错误: ", err)
+		}
+		cached = page
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+
+		page := cached
+		if live {
+			var err error
+			if page, err = render(r.Context()); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(page)
+	})
+	if *enablePprof {
+		registerPprof(mux)
+	}
+
+	logger.Printf("🌐 正在监听 %s，数据目录: %s\n", *addr, common.dataDir)
+	if err := http.ListenAndServe(*addr, mux); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	return 0
+}
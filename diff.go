@@ -0,0 +1,404 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"sort"
+	"strings"
+)
+
+// 对比状态
+const (
+	diffAdded     = "added"
+	diffRemoved   = "removed"
+	diffUnchanged = "unchanged"
+)
+
+// DiffNode 两棵树合并后的对比节点
+type DiffNode struct {
+	Name     string
+	Status   string // added / removed / unchanged
+	Children []*DiffNode
+}
+
+// CountDelta 单个分类在两个数据集之间的规则数量变化
+type CountDelta struct {
+	Name  string
+	Old   int
+	New   int
+	Delta int
+}
+
+// DiffCounts 对比两个数据集中都存在的分类的直接规则数量，按变化绝对值从大到小排序
+func DiffCounts(old, new *CategoryAnalyzer) []CountDelta {
+	var deltas []CountDelta
+	for name, newNode := range new.categories {
+		oldNode, exists := old.categories[name]
+		if !exists {
+			continue
+		}
+		oldCount, newCount := oldNode.RuleCount, newNode.RuleCount
+		deltas = append(deltas, CountDelta{Name: name, Old: oldCount, New: newCount, Delta: newCount - oldCount})
+	}
+
+	abs := func(n int) int {
+		if n < 0 {
+			return -n
+		}
+		return n
+	}
+	sort.Slice(deltas, func(i, j int) bool {
+		if abs(deltas[i].Delta) != abs(deltas[j].Delta) {
+			return abs(deltas[i].Delta) > abs(deltas[j].Delta)
+		}
+		return deltas[i].Name < deltas[j].Name
+	})
+
+	return deltas
+}
+
+// DiffResult 两个快照之间的分类变化，各列表均按名称排序
+type DiffResult struct {
+	Added   []string          // 只在新快照中出现的分类
+	Removed []string          // 只在旧快照中出现的分类
+	Changed []ChangedCategory // 两边都有但直接子分类不同的分类
+}
+
+// ChangedCategory 直接子分类发生变化的分类
+type ChangedCategory struct {
+	Name            string
+	AddedChildren   []string
+	RemovedChildren []string
+}
+
+// Diff 对比两个快照中的分类及其直接子分类
+// 同一分类在树中出现多次时合并它的子分类集合
+func Diff(old, new *TreeNode) DiffResult {
+	oldChildren, newChildren := directChildren(old), directChildren(new)
+
+	var result DiffResult
+	for name := range newChildren {
+		if _, exists := oldChildren[name]; !exists {
+			result.Added = append(result.Added, name)
+		}
+	}
+	for name, children := range oldChildren {
+		current, exists := newChildren[name]
+		if !exists {
+			result.Removed = append(result.Removed, name)
+			continue
+		}
+		change := ChangedCategory{Name: name}
+		for child := range current {
+			if !children[child] {
+				change.AddedChildren = append(change.AddedChildren, child)
+			}
+		}
+		for child := range children {
+			if !current[child] {
+				change.RemovedChildren = append(change.RemovedChildren, child)
+			}
+		}
+		if len(change.AddedChildren) > 0 || len(change.RemovedChildren) > 0 {
+			sort.Strings(change.AddedChildren)
+			sort.Strings(change.RemovedChildren)
+			result.Changed = append(result.Changed, change)
+		}
+	}
+
+	sort.Strings(result.Added)
+	sort.Strings(result.Removed)
+	sort.Slice(result.Changed, func(i, j int) bool {
+		return result.Changed[i].Name < result.Changed[j].Name
+	})
+	return result
+}
+
+// directChildren 返回树中每个分类（不含根节点）的直接子分类集合
+func directChildren(root *TreeNode) map[string]map[string]bool {
+	result := make(map[string]map[string]bool)
+	visited := make(map[*TreeNode]bool)
+
+	var walk func(node *TreeNode)
+	walk = func(node *TreeNode) {
+		for name, child := range node.Children {
+			if result[name] == nil {
+				result[name] = make(map[string]bool)
+			}
+			for grandchild := range child.Children {
+				result[name][grandchild] = true
+			}
+			if !visited[child] {
+				visited[child] = true
+				walk(child)
+			}
+		}
+	}
+	walk(root)
+
+	return result
+}
+
+// Print 打印可读的变化摘要
+func (r DiffResult) Print() {
+	if len(r.Added) == 0 && len(r.Removed) == 0 && len(r.Changed) == 0 {
+		fmt.Println("✅ 两个快照的分类结构相同")
+		return
+	}
+
+	fmt.Printf("🌳 新增 %d | 删除 %d | 变化 %d\n", len(r.Added), len(r.Removed), len(r.Changed))
+	for _, name := range r.Added {
+		fmt.Printf("+ %s\n", name)
+	}
+	for _, name := range r.Removed {
+		fmt.Printf("- %s\n", name)
+	}
+	for _, c := range r.Changed {
+		var parts []string
+		for _, child := range c.AddedChildren {
+			parts = append(parts, "+"+child)
+		}
+		for _, child := range c.RemovedChildren {
+			parts = append(parts, "-"+child)
+		}
+		fmt.Printf("~ %s (%s)\n", c.Name, strings.Join(parts, ", "))
+	}
+}
+
+// LoadJSON 读取 ExportJSON 生成的文件，并重建 Parents 指针
+// 同时支持默认格式（children 为数组）和 -json-legacy 格式（children 为映射）
+func LoadJSON(filename string) (*TreeNode, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var root TreeNode
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", filename, err)
+	}
+	linkTreeParents(&root)
+
+	return &root, nil
+}
+
+// linkTreeParents 为 JSON 中省略的 Parents 字段重新赋值
+// JSON 中共享的子分类会展开成多份，因此每个节点只有一个父节点
+func linkTreeParents(node *TreeNode) {
+	if node.Children == nil {
+		node.Children = make(map[string]*TreeNode)
+	}
+	for _, child := range node.Children {
+		child.Parents = []*TreeNode{node}
+		linkTreeParents(child)
+	}
+}
+
+// DiffTrees 按名称逐层合并两棵树，标记新增、删除和未变化的节点
+func DiffTrees(old, new *TreeNode) *DiffNode {
+	root := &DiffNode{Name: new.Name, Status: diffUnchanged}
+	root.Children = diffChildren(old, new)
+	return root
+}
+
+// diffChildren 对比同一位置下两组子节点，任一侧可以为 nil
+func diffChildren(old, new *TreeNode) []*DiffNode {
+	names := make(map[string]bool)
+	if old != nil {
+		for name := range old.Children {
+			names[name] = true
+		}
+	}
+	if new != nil {
+		for name := range new.Children {
+			names[name] = true
+		}
+	}
+
+	var sorted []string
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var result []*DiffNode
+	for _, name := range sorted {
+		var oldChild, newChild *TreeNode
+		if old != nil {
+			oldChild = old.Children[name]
+		}
+		if new != nil {
+			newChild = new.Children[name]
+		}
+
+		status := diffUnchanged
+		switch {
+		case oldChild == nil:
+			status = diffAdded
+		case newChild == nil:
+			status = diffRemoved
+		}
+		result = append(result, &DiffNode{
+			Name:     name,
+			Status:   status,
+			Children: diffChildren(oldChild, newChild),
+		})
+	}
+	return result
+}
+
+// hasChanges 判断节点或其后代是否有变化
+func (d *DiffNode) hasChanges() bool {
+	if d.Status != diffUnchanged {
+		return true
+	}
+	for _, child := range d.Children {
+		if child.hasChanges() {
+			return true
+		}
+	}
+	return false
+}
+
+// countStatus 统计各状态的节点数量（不含根节点）
+func (d *DiffNode) countStatus(counts map[string]int) {
+	for _, child := range d.Children {
+		counts[child.Status]++
+		child.countStatus(counts)
+	}
+}
+
+// ExportDiffHTML 将对比结果导出为HTML页面，有变化的分支默认展开
+func ExportDiffHTML(filename string, diff *DiffNode, oldName, newName string) error {
+	diffTemplate := `<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Domain List Community Tree Diff</title>
+    <style>
+        body {
+            font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif;
+            margin: 20px;
+            background-color: #f5f5f5;
+            color: #333;
+        }
+        .container {
+            max-width: 1200px;
+            margin: 0 auto;
+            background: white;
+            padding: 20px;
+            border-radius: 8px;
+            box-shadow: 0 2px 10px rgba(0,0,0,0.1);
+        }
+        .header {
+            text-align: center;
+            margin-bottom: 30px;
+        }
+        .stats {
+            background: #e3f2fd;
+            padding: 15px;
+            border-radius: 6px;
+            margin-bottom: 20px;
+            text-align: center;
+        }
+        .tree {
+            font-family: 'Courier New', monospace;
+            line-height: 1.6;
+        }
+        .children {
+            margin-left: 20px;
+        }
+        summary {
+            cursor: pointer;
+        }
+        .added { color: #2e7d32; font-weight: bold; }
+        .added:before { content: '+ '; }
+        .removed { color: #c62828; text-decoration: line-through; }
+        .removed:before { content: '- '; }
+        .unchanged { color: #9e9e9e; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>🌳 Domain List Community Tree Diff</h1>
+            <p>{{.OldName}} → {{.NewName}}</p>
+        </div>
+        <div class="stats">
+            新增 <strong class="added">{{.Added}}</strong> |
+            删除 <strong class="removed">{{.Removed}}</strong> |
+            未变化 <strong>{{.Unchanged}}</strong>
+        </div>
+        <div class="tree">
+            {{.TreeHTML}}
+        </div>
+    </div>
+</body>
+</html>`
+
+	tmpl, err := template.New("diff").Parse(diffTemplate)
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	for _, child := range diff.Children {
+		writeDiffHTML(&sb, child)
+	}
+
+	counts := make(map[string]int)
+	diff.countStatus(counts)
+
+	file, err := createOutputFile(filename)
+	if err != nil {
+		return err
+	}
+
+	err = tmpl.Execute(file, struct {
+		OldName   string
+		NewName   string
+		Added     int
+		Removed   int
+		Unchanged int
+		TreeHTML  template.HTML
+	}{
+		OldName:   oldName,
+		NewName:   newName,
+		Added:     counts[diffAdded],
+		Removed:   counts[diffRemoved],
+		Unchanged: counts[diffUnchanged],
+		TreeHTML:  template.HTML(sb.String()),
+	})
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	logger.Printf("✅ 对比HTML已保存: %s\n", filename)
+	return nil
+}
+
+// writeDiffHTML 生成单个对比节点的HTML
+func writeDiffHTML(sb *strings.Builder, node *DiffNode) {
+	name := template.HTMLEscapeString(node.Name)
+	if len(node.Children) == 0 {
+		fmt.Fprintf(sb, `<div class="%s">%s</div>`, node.Status, name)
+		return
+	}
+
+	open := ""
+	if node.hasChanges() {
+		open = " open"
+	}
+	fmt.Fprintf(sb, `<details%s><summary class="%s">%s</summary><div class="children">`, open, node.Status, name)
+	for _, child := range node.Children {
+		writeDiffHTML(sb, child)
+	}
+	sb.WriteString(`</div></details>`)
+}
@@ -0,0 +1,896 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ExportLeaves 导出所有叶子节点（不包含其他分类的文件）为CSV
+// 列为 name,class,depth,path，path 使用 " > " 连接父链
+func (ca *CategoryAnalyzer) ExportLeaves(filename string) error {
+	var names []string
+	for name, node := range ca.categories {
+		if len(node.Children) == 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	file, err := createOutputFile(filename)
+	if err != nil {
+		return err
+	}
+
+	w := csv.NewWriter(file)
+	if err := w.Write([]string{"name", "class", "depth", "path"}); err != nil {
+		file.Close()
+		return err
+	}
+	for _, name := range names {
+		path := ca.Path(ca.categories[name])
+		record := []string{name, ca.getNodeClass(name), strconv.Itoa(len(path)), strings.Join(path, " > ")}
+		if err := w.Write(record); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	logger.Printf("✅ 叶子节点CSV已保存: %s\n", filename)
+	return nil
+}
+
+// ExportCSV 导出所有分类为CSV，列为 name,parent,depth,child_count
+// 有多个父节点时 parent 和 depth 沿按名称排序的第一个父节点计算，与 ExportLeaves 一致
+func (ca *CategoryAnalyzer) ExportCSV(filename string) error {
+	var names []string
+	for name := range ca.categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	file, err := createOutputFile(filename)
+	if err != nil {
+		return err
+	}
+
+	w := csv.NewWriter(file)
+	if err := w.Write([]string{"name", "parent", "depth", "child_count"}); err != nil {
+		file.Close()
+		return err
+	}
+	for _, name := range names {
+		node := ca.categories[name]
+		parent := ""
+		if p := firstParent(node); p != nil {
+			parent = p.Name
+		}
+		record := []string{name, parent, strconv.Itoa(len(ca.Path(node))), strconv.Itoa(len(node.Children))}
+		if err := w.Write(record); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	logger.Printf("✅ 分类CSV已保存: %s\n", filename)
+	return nil
+}
+
+// cytoscapeNode Cytoscape.js 节点元素
+type cytoscapeNode struct {
+	Data struct {
+		ID    string `json:"id"`
+		Label string `json:"label"`
+		Class string `json:"class"`
+	} `json:"data"`
+}
+
+// cytoscapeEdge Cytoscape.js 边元素
+type cytoscapeEdge struct {
+	Data struct {
+		Source string `json:"source"`
+		Target string `json:"target"`
+	} `json:"data"`
+}
+
+// ExportCytoscape 导出为 Cytoscape.js 可直接加载的 elements JSON
+func (ca *CategoryAnalyzer) ExportCytoscape(filename string) error {
+	var names []string
+	for name := range ca.categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	elements := struct {
+		Nodes []cytoscapeNode `json:"nodes"`
+		Edges []cytoscapeEdge `json:"edges"`
+	}{
+		Nodes: []cytoscapeNode{},
+		Edges: []cytoscapeEdge{},
+	}
+
+	for _, name := range names {
+		var node cytoscapeNode
+		node.Data.ID = name
+		node.Data.Label = name
+		node.Data.Class = ca.getNodeClass(name)
+		elements.Nodes = append(elements.Nodes, node)
+
+		var children []string
+		for child := range ca.categories[name].Children {
+			children = append(children, child)
+		}
+		sort.Strings(children)
+
+		for _, child := range children {
+			var edge cytoscapeEdge
+			edge.Data.Source = name
+			edge.Data.Target = child
+			elements.Edges = append(elements.Edges, edge)
+		}
+	}
+
+	jsonData, err := json.MarshalIndent(map[string]interface{}{"elements": elements}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := writeOutputFile(filename, jsonData); err != nil {
+		return err
+	}
+
+	logger.Printf("✅ Cytoscape JSON已保存: %s\n", filename)
+	return nil
+}
+
+// ExportList 导出所有分类名，每行一个并按名称排序
+// 子目录中的文件使用相对路径（如 sub/name），与 ca.categories 的键一致
+func (ca *CategoryAnalyzer) ExportList(filename string) error {
+	names := make([]string, 0, len(ca.categories))
+	for name := range ca.categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name + "\n")
+	}
+	if err := writeOutputFile(filename, []byte(b.String())); err != nil {
+		return err
+	}
+
+	logger.Printf("✅ 分类列表已保存: %s\n", filename)
+	return nil
+}
+
+// ExportMarkdown 导出 Markdown 嵌套列表，每层缩进两个空格
+// 每个分类链接到其原始源文件，子节点顺序与控制台输出一致
+func (ca *CategoryAnalyzer) ExportMarkdown(filename string) error {
+	var b strings.Builder
+
+	var walk func(node *TreeNode, depth int)
+	walk = func(node *TreeNode, depth int) {
+		for _, name := range sortedChildNames(node) {
+			fmt.Fprintf(&b, "%s- [%s](%s)\n", strings.Repeat("  ", depth), name, ca.sourceURL(name))
+			walk(node.Children[name], depth+1)
+		}
+	}
+	walk(ca.tree, 0)
+
+	if err := writeOutputFile(filename, []byte(b.String())); err != nil {
+		return err
+	}
+
+	logger.Printf("✅ Markdown文件已保存: %s\n", filename)
+	return nil
+}
+
+// ExportGeositeText 为每个顶级分类写出展开全部 include 后的规则文件，可直接交给官方生成器
+// 规则保留原文中的类型前缀和属性，重复规则只保留第一次出现
+func (ca *CategoryAnalyzer) ExportGeositeText(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	for _, name := range sortedChildNames(ca.tree) {
+		rules := ca.expandRules(name, nil, make(map[string]bool))
+
+		var b strings.Builder
+		seen := make(map[string]bool)
+		for _, rule := range rules {
+			line := geositeLine(rule)
+			if !seen[line] {
+				seen[line] = true
+				b.WriteString(line + "\n")
+			}
+		}
+		if err := writeOutputFile(filepath.Join(dir, name), []byte(b.String())); err != nil {
+			return err
+		}
+	}
+
+	logger.Printf("✅ geosite 规则文件已保存: %s (%d 个分类)\n", dir, len(ca.tree.Children))
+	return nil
+}
+
+// singBoxRuleSet sing-box 源格式规则集
+type singBoxRuleSet struct {
+	Version int           `json:"version"`
+	Rules   []singBoxRule `json:"rules"`
+}
+
+// singBoxRule sing-box 的一条 headless 规则
+type singBoxRule struct {
+	Domain        []string `json:"domain,omitempty"`
+	DomainSuffix  []string `json:"domain_suffix,omitempty"`
+	DomainKeyword []string `json:"domain_keyword,omitempty"`
+}
+
+// ExportSingBox 将分类展开全部 include 后导出为 sing-box 规则集 JSON
+// full → domain，domain → domain_suffix，keyword → domain_keyword，regexp 规则跳过并给出警告
+func (ca *CategoryAnalyzer) ExportSingBox(category, filename string) error {
+	if _, exists := ca.categories[ca.resolveInclude(category)]; !exists {
+		return fmt.Errorf("分类不存在: %s", category)
+	}
+	rules := ca.expandRules(category, nil, make(map[string]bool))
+
+	var rule singBoxRule
+	seen := make(map[string]bool)
+	skipped := 0
+	for _, r := range rules {
+		key := r.Type + ":" + r.Value
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		switch r.Type {
+		case "full":
+			rule.Domain = append(rule.Domain, r.Value)
+		case "domain":
+			rule.DomainSuffix = append(rule.DomainSuffix, r.Value)
+		case "keyword":
+			rule.DomainKeyword = append(rule.DomainKeyword, r.Value)
+		case "regexp":
+			skipped++
+		}
+	}
+	if skipped > 0 {
+		logger.Printf("⚠️  %s: 跳过 %d 条 regexp 规则，sing-box 规则集不导出正则\n", category, skipped)
+	}
+
+	jsonData, err := json.MarshalIndent(singBoxRuleSet{Version: 1, Rules: []singBoxRule{rule}}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeOutputFile(filename, jsonData); err != nil {
+		return err
+	}
+
+	logger.Printf("✅ sing-box 规则集已保存: %s\n", filename)
+	return nil
+}
+
+// expandRules 递归展开分类的 include，只返回具体规则
+// attrs 为 include 行上的属性：@attr 只保留带该属性的规则，@-attr 排除带该属性的规则
+// path 记录当前展开链，遇到循环 include 时跳过回边
+// 规则取自构建树时的解析结果，同一分类经多条路径到达时不会重复读取文件，每次只按 attrs 过滤
+func (ca *CategoryAnalyzer) expandRules(name string, attrs []string, path map[string]bool) []Rule {
+	name = ca.resolveInclude(name)
+	if path[name] {
+		return nil
+	}
+	// 构建树时跳过的文件没有解析结果，同样不展开，与树中的规则数一致
+	rules, parsed := ca.rules[name]
+	if _, exists := ca.categories[name]; !exists || !parsed {
+		return nil
+	}
+	path[name] = true
+	defer delete(path, name)
+
+	var expanded []Rule
+	for _, rule := range rules {
+		if rule.Type == "include" {
+			expanded = append(expanded, ca.expandRules(rule.Value, append(attrs, rule.Attributes...), path)...)
+		} else if matchAttributes(rule, attrs) {
+			expanded = append(expanded, rule)
+		}
+	}
+	return expanded
+}
+
+// Flatten 返回分类通过 include 传递可达的全部具体规则，格式为 类型:值，去重并排序
+// 循环 include 只展开一次，无法解析的 include 被跳过（已在构建树时记录到 MissingIncludes）
+func (ca *CategoryAnalyzer) Flatten(name string) ([]string, error) {
+	resolved := ca.resolveInclude(name)
+	if _, exists := ca.categories[resolved]; !exists {
+		return nil, fmt.Errorf("未知分类: %s", name)
+	}
+
+	rules := ca.expandRules(resolved, nil, make(map[string]bool))
+
+	seen := make(map[string]bool, len(rules))
+	var result []string
+	for _, rule := range rules {
+		line := rule.Type + ":" + rule.Value
+		if !seen[line] {
+			seen[line] = true
+			result = append(result, line)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// matchAttributes 判断规则是否满足 include 行上的全部属性条件
+func matchAttributes(rule Rule, attrs []string) bool {
+	for _, attr := range attrs {
+		if excluded, ok := strings.CutPrefix(attr, "@-"); ok {
+			if contains(rule.Attributes, "@"+excluded) {
+				return false
+			}
+		} else if !contains(rule.Attributes, attr) {
+			return false
+		}
+	}
+	return true
+}
+
+// geositeLine 将规则还原为数据文件中的一行，类型前缀保持原文的写法
+func geositeLine(rule Rule) string {
+	line := rule.Prefix + rule.Value
+	if len(rule.Attributes) > 0 {
+		line += " " + strings.Join(rule.Attributes, " ")
+	}
+	return line
+}
+
+// ExportAdjacency 导出文件中原始的 include 关系 {"分类": ["include 目标", ...]}
+// 与树不同，这里保留无法解析的目标、自身 include 和循环，用于审计；键和值均排序并去重
+func (ca *CategoryAnalyzer) ExportAdjacency(filename string) error {
+	adjacency := make(map[string][]string, len(ca.categories))
+	for name := range ca.categories {
+		// 构建树时跳过的文件没有 include 关系，不再重新读取
+		if ca.skippedFiles[name] {
+			adjacency[name] = []string{}
+			continue
+		}
+		includes, err := ca.parseIncludes(filepath.Join(ca.dataDir, name))
+		if err != nil {
+			return err
+		}
+		sort.Strings(includes)
+		targets := []string{}
+		for i, target := range includes {
+			if i == 0 || target != includes[i-1] {
+				targets = append(targets, target)
+			}
+		}
+		adjacency[name] = targets
+	}
+
+	// encoding/json 按键排序输出 map
+	jsonData, err := json.MarshalIndent(adjacency, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeOutputFile(filename, jsonData); err != nil {
+		return err
+	}
+
+	logger.Printf("✅ include 邻接表已保存: %s\n", filename)
+	return nil
+}
+
+// jsonlNode ExportJSONL 每行输出的节点
+type jsonlNode struct {
+	Name     string   `json:"name"`
+	Parent   string   `json:"parent"` // 顶级分类为空字符串
+	Depth    int      `json:"depth"`  // 顶级分类为 1
+	Children []string `json:"children"`
+}
+
+// ExportJSONL 按稳定的先序遍历导出 JSON Lines，每行一个节点
+// 被多个分类 include 的节点每个 (父节点, 节点) 组合只输出一行，深度取先序遍历中第一次出现时的深度
+func (ca *CategoryAnalyzer) ExportJSONL(filename string) error {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	emitted := make(map[[2]string]bool)
+
+	var walk func(node *TreeNode, parent string, depth int) error
+	walk = func(node *TreeNode, parent string, depth int) error {
+		// 同一父节点经不同路径到达时，它的子树已经输出过
+		pair := [2]string{parent, node.Name}
+		if emitted[pair] {
+			return nil
+		}
+		emitted[pair] = true
+
+		children := sortedChildNames(node)
+		if err := encoder.Encode(jsonlNode{
+			Name:     node.Name,
+			Parent:   parent,
+			Depth:    depth,
+			Children: append([]string{}, children...),
+		}); err != nil {
+			return err
+		}
+		for _, name := range children {
+			if err := walk(node.Children[name], node.Name, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, name := range sortedChildNames(ca.tree) {
+		if err := walk(ca.tree.Children[name], "", 1); err != nil {
+			return err
+		}
+	}
+
+	if err := writeOutputFile(filename, b.Bytes()); err != nil {
+		return err
+	}
+
+	logger.Printf("✅ JSON Lines文件已保存: %s\n", filename)
+	return nil
+}
+
+// dotEscaper 转义 DOT 双引号字符串中的特殊字符
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// dotID 返回加引号的 DOT 节点 ID，分类名中包含 - 和 / 等字符
+func dotID(name string) string {
+	return `"` + dotEscaper.Replace(name) + `"`
+}
+
+// ExportDOT 导出 Graphviz digraph，每条 include 关系一条边
+// 共享的子分类会显示为多条汇聚的边，可通过 dot -Tsvg 渲染
+func (ca *CategoryAnalyzer) ExportDOT(filename string) error {
+	var b strings.Builder
+	if err := ca.RenderDOT(&b); err != nil {
+		return err
+	}
+	if err := writeOutputFile(filename, []byte(b.String())); err != nil {
+		return err
+	}
+
+	logger.Printf("✅ DOT文件已保存: %s\n", filename)
+	return nil
+}
+
+// RenderDOT 将 ExportDOT 的内容写入 w
+func (ca *CategoryAnalyzer) RenderDOT(w io.Writer) error {
+	var names []string
+	for name := range ca.categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", dotID(ca.tree.Name))
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=rounded, fontname=\"sans-serif\"];\n\n")
+
+	for _, name := range names {
+		color := nodeClassColors[ca.getNodeClass(name)]
+		fmt.Fprintf(&b, "  %s [color=%s, fontcolor=%s];\n", dotID(name), dotID(color), dotID(color))
+	}
+	b.WriteString("\n")
+
+	for _, name := range names {
+		for _, child := range sortedChildNames(ca.categories[name]) {
+			fmt.Fprintf(&b, "  %s -> %s;\n", dotID(name), dotID(child))
+		}
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// graphML GraphML 文档结构
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+// graphMLKey GraphML 属性声明
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+// graphMLGraph GraphML 图
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+// graphMLNode GraphML 节点
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+// graphMLEdge GraphML 有向边
+type graphMLEdge struct {
+	ID     string `xml:"id,attr"`
+	Source string `xml:"source,attr"`
+	Target string `xml:"target,attr"`
+}
+
+// graphMLData GraphML 节点属性值
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// ExportGraphML 导出 GraphML，供 yEd、Gephi 等图分析工具使用
+// 节点 ID 为分类名（由 encoding/xml 转义），每条 include 关系一条有向边
+func (ca *CategoryAnalyzer) ExportGraphML(filename string) error {
+	var names []string
+	for name := range ca.categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	doc := graphML{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "label", For: "node", AttrName: "label", AttrType: "string"},
+			{ID: "class", For: "node", AttrName: "class", AttrType: "string"},
+		},
+		Graph: graphMLGraph{ID: ca.tree.Name, EdgeDefault: "directed"},
+	}
+	for _, name := range names {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID: name,
+			Data: []graphMLData{
+				{Key: "label", Value: name},
+				{Key: "class", Value: ca.getNodeClass(name)},
+			},
+		})
+		for _, child := range sortedChildNames(ca.categories[name]) {
+			doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+				ID:     fmt.Sprintf("e%d", len(doc.Graph.Edges)),
+				Source: name,
+				Target: child,
+			})
+		}
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeOutputFile(filename, append([]byte(xml.Header), append(data, '\n')...)); err != nil {
+		return err
+	}
+
+	logger.Printf("✅ GraphML文件已保存: %s\n", filename)
+	return nil
+}
+
+// svgRow SVG 树中的一行，对应一个渲染出的节点
+type svgRow struct {
+	name   string
+	class  string
+	depth  int
+	parent int // 父节点所在行，根节点为 -1
+}
+
+// SVG 缩进布局的尺寸（像素），标签使用等宽字体以便估算宽度
+const (
+	svgRowHeight = 28
+	svgBoxHeight = 20
+	svgIndent    = 24
+	svgCharWidth = 7.2
+	svgPadding   = 8
+	svgMargin    = 10
+)
+
+// ExportSVG 以缩进布局将树直接绘制为 SVG，不依赖 Graphviz 等外部工具
+// 每个渲染出的节点占一行，共享的子分类在每个父节点下重复出现，方框颜色与 HTML 一致
+func (ca *CategoryAnalyzer) ExportSVG(filename string) error {
+	var rows []svgRow
+	var walk func(node *TreeNode, depth, parent int)
+	walk = func(node *TreeNode, depth, parent int) {
+		class := ""
+		if depth > 0 {
+			class = ca.getNodeClass(node.Name)
+		}
+		rows = append(rows, svgRow{name: node.Name, class: class, depth: depth, parent: parent})
+		index := len(rows) - 1
+		for _, name := range sortedChildNames(node) {
+			walk(node.Children[name], depth+1, index)
+		}
+	}
+	walk(ca.tree, 0, -1)
+
+	boxWidth := func(row svgRow) float64 {
+		return float64(len([]rune(row.name)))*svgCharWidth + 2*svgPadding
+	}
+	rowX := func(row svgRow) float64 {
+		return float64(svgMargin + row.depth*svgIndent)
+	}
+	rowY := func(index int) float64 {
+		return float64(svgMargin + index*svgRowHeight)
+	}
+
+	width := 0.0
+	for _, row := range rows {
+		width = max(width, rowX(row)+boxWidth(row)+svgMargin)
+	}
+	height := float64(2*svgMargin + (len(rows)-1)*svgRowHeight + svgBoxHeight)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f">`+"\n",
+		width, height, width, height)
+	b.WriteString(`  <rect width="100%" height="100%" fill="#ffffff"/>` + "\n")
+
+	// 连接线：从父节点方框底部竖直向下，再水平接到子节点方框左侧中点
+	b.WriteString(`  <g fill="none" stroke="#bbbbbb" stroke-width="1">` + "\n")
+	for i, row := range rows {
+		if row.parent < 0 {
+			continue
+		}
+		parent := rows[row.parent]
+		x := rowX(parent) + svgIndent/2
+		fmt.Fprintf(&b, `    <path d="M %.1f %.1f V %.1f H %.1f"/>`+"\n",
+			x, rowY(row.parent)+svgBoxHeight, rowY(i)+svgBoxHeight/2, rowX(row))
+	}
+	b.WriteString("  </g>\n")
+
+	b.WriteString(`  <g font-family="monospace" font-size="12">` + "\n")
+	for i, row := range rows {
+		color, ok := nodeClassColors[row.class]
+		if !ok {
+			color = "#333333"
+		}
+		x, y := rowX(row), rowY(i)
+		fmt.Fprintf(&b, `    <rect x="%.1f" y="%.1f" width="%.1f" height="%d" rx="4" fill="#ffffff" stroke="%s"/>`+"\n",
+			x, y, boxWidth(row), svgBoxHeight, color)
+		fmt.Fprintf(&b, `    <text x="%.1f" y="%.1f" fill="%s">`, x+svgPadding, y+svgBoxHeight/2+4, color)
+		xml.EscapeText(&b, []byte(row.name))
+		b.WriteString("</text>\n")
+	}
+	b.WriteString("  </g>\n</svg>\n")
+
+	if err := writeOutputFile(filename, []byte(b.String())); err != nil {
+		return err
+	}
+
+	logger.Printf("✅ SVG文件已保存: %s\n", filename)
+	return nil
+}
+
+// mermaidEscaper 转义 Mermaid 标签中有特殊含义的字符
+var mermaidEscaper = strings.NewReplacer(
+	`"`, "#quot;",
+	"<", "#lt;",
+	">", "#gt;",
+	"&", "#amp;",
+)
+
+// ExportMermaid 导出 Mermaid graph TD 流程图，每条 include 关系一行
+// maxDepth > 0 时只保留深度不超过 maxDepth 的节点（顶级为 1）
+func (ca *CategoryAnalyzer) ExportMermaid(filename string, maxDepth int) error {
+	// 节点 ID 使用序号，分类名只出现在带引号的标签里
+	ids := make(map[*TreeNode]string)
+	var b strings.Builder
+	b.WriteString("graph TD\n")
+
+	nodeRef := func(node *TreeNode) string {
+		if id, ok := ids[node]; ok {
+			return id
+		}
+		id := fmt.Sprintf("n%d", len(ids))
+		ids[node] = id
+		return fmt.Sprintf("%s[\"%s\"]", id, mermaidEscaper.Replace(node.Name))
+	}
+
+	// 按层遍历，共享的子分类在最浅的深度展开一次
+	level := make([]*TreeNode, 0, len(ca.tree.Children))
+	for _, name := range sortedChildNames(ca.tree) {
+		level = append(level, ca.tree.Children[name])
+	}
+	expanded := make(map[*TreeNode]bool)
+	for depth := 1; len(level) > 0; depth++ {
+		var next []*TreeNode
+		for _, node := range level {
+			if expanded[node] {
+				continue
+			}
+			expanded[node] = true
+
+			// 没有画出任何边的节点（顶级叶子、深度上限处的顶级节点）单独声明
+			atLimit := maxDepth > 0 && depth >= maxDepth
+			if _, declared := ids[node]; !declared && (atLimit || len(node.Children) == 0) {
+				fmt.Fprintf(&b, "    %s\n", nodeRef(node))
+			}
+			if atLimit {
+				continue
+			}
+			for _, name := range sortedChildNames(node) {
+				child := node.Children[name]
+				fmt.Fprintf(&b, "    %s --> %s\n", nodeRef(node), nodeRef(child))
+				next = append(next, child)
+			}
+		}
+		level = next
+	}
+
+	if err := writeOutputFile(filename, []byte(b.String())); err != nil {
+		return err
+	}
+
+	logger.Printf("✅ Mermaid文件已保存: %s\n", filename)
+	return nil
+}
+
+// tomlTree TOML 导出结构
+// TOML 不适合表达深层嵌套，因此使用扁平的边表：
+//
+//	root = "domain-list-community"
+//	roots = ["category-ads-all", ...]   # 顶级分类
+//
+//	[[category]]                        # 每个分类一条，按名称排序
+//	name = "google"
+//	class = "company"
+//
+//	[[edge]]                            # 每条 include 关系一条
+//	from = "google"
+//	to = "youtube"
+type tomlTree struct {
+	Root       string         `toml:"root"`
+	Roots      []string       `toml:"roots"`
+	Categories []tomlCategory `toml:"category"`
+	Edges      []tomlEdge     `toml:"edge"`
+}
+
+type tomlCategory struct {
+	Name  string `toml:"name"`
+	Class string `toml:"class"`
+}
+
+type tomlEdge struct {
+	From string `toml:"from"`
+	To   string `toml:"to"`
+}
+
+// ExportTOML 以边表形式导出为TOML格式
+func (ca *CategoryAnalyzer) ExportTOML(filename string) error {
+	doc := tomlTree{Root: ca.tree.Name, Roots: []string{}}
+	for name := range ca.tree.Children {
+		doc.Roots = append(doc.Roots, name)
+	}
+	sort.Strings(doc.Roots)
+
+	var names []string
+	for name := range ca.categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		doc.Categories = append(doc.Categories, tomlCategory{Name: name, Class: ca.getNodeClass(name)})
+
+		var children []string
+		for child := range ca.categories[name].Children {
+			children = append(children, child)
+		}
+		sort.Strings(children)
+		for _, child := range children {
+			doc.Edges = append(doc.Edges, tomlEdge{From: name, To: child})
+		}
+	}
+
+	file, err := createOutputFile(filename)
+	if err != nil {
+		return err
+	}
+
+	encoder := toml.NewEncoder(file)
+	encoder.Indent = ""
+	if err := encoder.Encode(doc); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	logger.Printf("✅ TOML文件已保存: %s\n", filename)
+	return nil
+}
+
+// leafDomains 读取分类文件中的 domain/full 规则值（去掉属性），构建树时跳过的文件没有规则
+func (ca *CategoryAnalyzer) leafDomains(name string) ([]string, error) {
+	if ca.skippedFiles[name] {
+		return nil, nil
+	}
+	rules, err := ca.parseRules(filepath.Join(ca.dataDir, name))
+	if err != nil {
+		return nil, err
+	}
+
+	var domains []string
+	for _, rule := range rules {
+		if rule.Type != "domain" && rule.Type != "full" {
+			continue
+		}
+		if rule.Value != "" {
+			domains = append(domains, rule.Value)
+		}
+	}
+	return domains, nil
+}
+
+// ExportEntrySamples 为每个叶子分类导出最多 k 条域名样例
+// 样例取排序后的前 k 条 domain/full 规则，保证每次输出一致
+func (ca *CategoryAnalyzer) ExportEntrySamples(filename string, k int) error {
+	samples := make(map[string][]string)
+	for name, node := range ca.categories {
+		if len(node.Children) > 0 {
+			continue
+		}
+
+		domains, err := ca.leafDomains(name)
+		if err != nil {
+			return err
+		}
+		sort.Strings(domains)
+		if len(domains) > k {
+			domains = domains[:k]
+		}
+		if len(domains) > 0 {
+			samples[name] = domains
+		}
+	}
+
+	jsonData, err := json.MarshalIndent(samples, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := writeOutputFile(filename, jsonData); err != nil {
+		return err
+	}
+
+	logger.Printf("✅ 域名样例已保存: %s\n", filename)
+	return nil
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+var update = flag.Bool("update", false, "用当前输出重新生成 testdata/golden 中的文件")
+
+// goldenAnalyzer 构建 testdata/data 中的示例数据，包含属性、跨文件 include 和 google ↔ youtube 循环
+func goldenAnalyzer(t *testing.T) *CategoryAnalyzer {
+	t.Helper()
+	return buildFixture(t, filepath.Join("testdata", "data"))
+}
+
+// includeEdgeCount 返回树中 include 关系的总数
+func includeEdgeCount(ca *CategoryAnalyzer) int {
+	count := 0
+	for _, node := range ca.categories {
+		count += len(node.Children)
+	}
+	return count
+}
+
+func TestGolden(t *testing.T) {
+	tests := []struct {
+		file   string
+		render func(ca *CategoryAnalyzer, w io.Writer) error
+	}{
+		{"tree.json", (*CategoryAnalyzer).RenderJSON},
+		{"tree.dot", (*CategoryAnalyzer).RenderDOT},
+		{"tree.txt", func(ca *CategoryAnalyzer, w io.Writer) error {
+			ca.renderTree(w, false)
+			return nil
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.render(goldenAnalyzer(t), &buf); err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join("testdata", "golden", tt.file)
+			if *update {
+				if err := os.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v（使用 go test -update 生成）", err)
+			}
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Errorf("%s 与 golden 文件不一致，确认改动后使用 go test -update 更新\n得到:\n%s", tt.file, buf.String())
+			}
+		})
+	}
+}
+
+func TestGoldenCycle(t *testing.T) {
+	ca := goldenAnalyzer(t)
+	want := [][]string{{"google", "youtube", "google"}}
+	if got := ca.Cycles(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Cycles() = %v，期望 %v", got, want)
+	}
+}
+
+func TestRenderDOTEdgeCount(t *testing.T) {
+	ca := goldenAnalyzer(t)
+	var buf bytes.Buffer
+	if err := ca.RenderDOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	edges := 0
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.Contains(line, " -> ") {
+			edges++
+		}
+	}
+	if want := includeEdgeCount(ca); edges != want {
+		t.Errorf("DOT 边数 = %d，期望 %d", edges, want)
+	}
+}
+
+func TestExportMermaid(t *testing.T) {
+	ca := goldenAnalyzer(t)
+	topEdges := 0
+	for _, node := range ca.tree.Children {
+		topEdges += len(node.Children)
+	}
+	tests := []struct {
+		name     string
+		maxDepth int
+		edges    int
+		decls    int // 单独声明、没有画出边的节点
+	}{
+		{"unlimited", 0, includeEdgeCount(ca), 0},
+		{"depth 1", 1, 0, len(ca.tree.Children)},
+		{"depth 2", 2, topEdges, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "tree.mmd")
+			if err := ca.ExportMermaid(filename, tt.maxDepth); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+			if lines[0] != "graph TD" {
+				t.Fatalf("第一行 = %q，期望 graph TD", lines[0])
+			}
+			edges, decls := 0, 0
+			for _, line := range lines[1:] {
+				if strings.Contains(line, " --> ") {
+					edges++
+				} else {
+					decls++
+				}
+			}
+			if edges != tt.edges || decls != tt.decls {
+				t.Errorf("边 %d、单独声明 %d，期望边 %d、单独声明 %d\n%s", edges, decls, tt.edges, tt.decls, data)
+			}
+		})
+	}
+}
+
+// treeShape 返回以 node 为根的子树中每条父子路径，用于比较两棵树的结构
+func treeShape(node *TreeNode, prefix string, shape map[string]int) {
+	for name, child := range node.Children {
+		path := prefix + "/" + name
+		shape[path] = child.RuleCount
+		treeShape(child, path, shape)
+	}
+}
+
+func TestLoadJSONRoundTrip(t *testing.T) {
+	for _, legacy := range []bool{false, true} {
+		name := "array"
+		if legacy {
+			name = "legacy"
+		}
+		t.Run(name, func(t *testing.T) {
+			ca := goldenAnalyzer(t)
+			ca.jsonLegacy = legacy
+			filename := filepath.Join(t.TempDir(), "tree.json")
+			if err := ca.ExportJSON(filename); err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := LoadJSON(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want, got := make(map[string]int), make(map[string]int)
+			treeShape(ca.tree, "", want)
+			treeShape(loaded, "", got)
+			if legacy {
+				// 旧格式不包含规则数
+				for path := range want {
+					want[path] = 0
+				}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("读回的树 = %v，期望 %v", got, want)
+			}
+			for _, child := range loaded.Children {
+				if len(child.Parents) != 1 || child.Parents[0] != loaded {
+					t.Errorf("%s 的 Parents 没有指向根节点", child.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestYAMLMatchesJSON(t *testing.T) {
+	ca := goldenAnalyzer(t)
+	dir := t.TempDir()
+	jsonFile, yamlFile := filepath.Join(dir, "tree.json"), filepath.Join(dir, "tree.yaml")
+	if err := ca.ExportJSON(jsonFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := ca.ExportYAML(yamlFile); err != nil {
+		t.Fatal(err)
+	}
+
+	var fromJSON, fromYAML jsonNode
+	data, err := os.ReadFile(jsonFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	data, err = os.ReadFile(yamlFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := yaml.Unmarshal(data, &fromYAML); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromYAML, fromJSON) {
+		t.Errorf("YAML 与 JSON 的结构不一致")
+	}
+}
+
+func TestExportJSONLUniquePairs(t *testing.T) {
+	dir := writeFixture(t, map[string]string{
+		"a": "include:b\ninclude:c\n",
+		"b": "include:d\n",
+		"c": "include:d\n",
+		"d": "include:e\n",
+		"e": "e.com\n",
+	})
+	ca := buildFixture(t, dir)
+	filename := filepath.Join(t.TempDir(), "tree.jsonl")
+	if err := ca.ExportJSONL(filename); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pairs []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var n jsonlNode
+		if err := json.Unmarshal([]byte(line), &n); err != nil {
+			t.Fatal(err)
+		}
+		pairs = append(pairs, n.Parent+">"+n.Name)
+	}
+	sort.Strings(pairs)
+	want := []string{">a", "a>b", "a>c", "b>d", "c>d", "d>e"}
+	if !reflect.DeepEqual(pairs, want) {
+		t.Errorf("(父节点, 节点) = %v，期望 %v", pairs, want)
+	}
+}
+
+func TestExportGeositeTextKeepsPrefixes(t *testing.T) {
+	dir := writeFixture(t, map[string]string{
+		"a": "include:b\ndomain:a.com\nfull:x.a.com @cn\n",
+		"b": "b.com\ndomain:c.com @cn\n",
+	})
+	ca := buildFixture(t, dir)
+	out := t.TempDir()
+	if err := ca.ExportGeositeText(out); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(out, "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "b.com\ndomain:c.com @cn\ndomain:a.com\nfull:x.a.com @cn\n"
+	if string(data) != want {
+		t.Errorf("geosite 输出 =\n%s期望\n%s", data, want)
+	}
+}
+
+func TestFlattenReusesParsedRules(t *testing.T) {
+	dir := writeFixture(t, map[string]string{
+		"a": "include:b\ninclude:c @cn\n",
+		"b": "include:d\n",
+		"c": "include:d\n",
+		"d": "d.com @cn\nx.com\n",
+	})
+	ca := buildFixture(t, dir)
+	// 展开只使用构建树时的解析结果，不再读取文件
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ca.Flatten("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"domain:d.com", "domain:x.com"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Flatten(a) = %v，期望 %v", got, want)
+	}
+	if rules := ca.expandRules("c", []string{"@cn"}, make(map[string]bool)); len(rules) != 1 || rules[0].Value != "d.com" {
+		t.Errorf("按 @cn 展开 c = %v，期望只有 d.com", rules)
+	}
+}
@@ -2,43 +2,383 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"html/template"
 	"io"
 	"io/fs"
+	"log"
+	"net/http"
+	"net/http/pprof"
+	"net/url"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"runtime/debug"
 	"sort"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"gopkg.in/yaml.v3"
 )
 
 // TreeNode 表示树结构中的一个节点
 type TreeNode struct {
-	Name     string               `json:"name"`
-	Children map[string]*TreeNode `json:"children,omitempty"`
-	Parent   *TreeNode            `json:"-"`
+	Name       string               `json:"name"`
+	Children   map[string]*TreeNode `json:"children,omitempty"`
+	Parents    []*TreeNode          `json:"-"` // 所有包含该节点的分类，按名称排序
+	RuleCounts map[string]int       `json:"-"` // 文件中直接声明的各类型规则数量
+	RuleCount  int                  `json:"-"` // 文件中直接声明的规则总数
+	RuleLines  []int                `json:"-"` // 直接声明的规则所在行号
+	Attributes map[string]int       `json:"-"` // 文件中出现的 @ 属性及次数
+
+	// ChildAttributes include 子分类时附带的属性选择器，如 include:geolocation-!cn @cn
+	ChildAttributes map[string][]string `json:"-"`
+
+	// Context 表示该节点只是为了展示上下文而保留的祖先（视图中置灰显示）
+	Context bool `json:"-"`
+
+	// Truncated 表示节点位于 -build-depth 限制处，它的 include 没有被构建
+	Truncated bool `json:"-"`
+
+	// SourceLines 源文件的原始内容，仅在开启 --source-lines 时填充，用于离线查看
+	SourceLines []string `json:"-"`
+
+	// 溯源信息，仅在开启 --provenance 时填充
+	Source   string       `json:"source,omitempty"`
+	Includes []IncludeRef `json:"includes,omitempty"`
+}
+
+// IncludeRef 记录一条 include 在源文件中的位置
+type IncludeRef struct {
+	Name string `json:"name" yaml:"name"`
+	Line int    `json:"line" yaml:"line"`
+	Attr string `json:"attr,omitempty" yaml:"attr,omitempty"`
 }
 
+// ruleTypes 参与计数的规则类型（include 不计入）
+var ruleTypes = []string{"domain", "full", "keyword", "regexp"}
+
+// defaultMaxFileSize 单个数据文件的默认大小上限
+const defaultMaxFileSize = 16 << 20
+
+// defaultCommentMarkers 默认识别的注释标记
+var defaultCommentMarkers = []string{"#", "//"}
+
+// defaultSourceRef 源码链接默认指向的分支
+const defaultSourceRef = "refs/heads/master"
+
+// defaultSourceBase 源码链接默认使用的 raw 地址前缀，其后依次拼接 sourceRef、data 和分类名
+const defaultSourceBase = "https://raw.githubusercontent.com/v2ray/domain-list-community"
+
+// defaultRootName 虚拟根节点的默认名称
+const defaultRootName = "domain-list-community"
+
 // CategoryAnalyzer 分类分析器
 type CategoryAnalyzer struct {
-	dataDir        string
-	categories     map[string]*TreeNode
-	tree           *TreeNode
-	processedFiles map[string]bool
+	dataDir         string
+	maxFileSize     int64
+	categories      map[string]*TreeNode
+	tree            *TreeNode
+	processedFiles  map[string]bool
+	skippedFiles    map[string]bool   // 构建树时解析失败（如超过 -max-file-size）而跳过的分类
+	rules           map[string][]Rule // 构建树时解析出的各分类全部规则，展开 include 时复用
+	html            htmlOptions
+	provenance      bool           // 是否在节点上记录源文件和 include 行号
+	commentMarkers  []string       // 行首或空白后出现即视为注释开始的标记
+	sourceRef       string         // 源码链接使用的分支或提交
+	sourceBase      string         // 源码链接的 raw 地址前缀，如 fork 或私有镜像
+	useGoGit        bool           // 自动下载数据时使用 go-git 而不是 git 命令
+	proxy           string         // 克隆时使用的 HTTP(S) 代理，优先于环境变量
+	retries         int            // 克隆失败时的最多尝试次数
+	location        *time.Location // HTML 更新时间使用的时区，为 nil 时使用 defaultTimezone
+	consoleCounts   bool           // 控制台树中显示每个节点的规则总数
+	renderDepth     int            // 控制台和HTML只展示到第几层（顶级为 1），<= 0 表示不限制
+	buildDepth      int            // 构建树时保留的最大 include 深度（顶级为 1），<= 0 表示不限制
+	keepSourceLines bool           // 解析时保留源文件原始内容，HTML 中可展开查看
+	jsonLegacy      bool           // ExportJSON 使用旧的 name/children 映射格式
+	consoleColor    bool           // 控制台树按节点类型着色
+
+	// 自定义HTML模板，为空时使用 defaultHTMLTemplate
+	htmlTemplateName string
+	htmlTemplate     string
+
+	missingIncludes []MissingInclude
+	selfIncludes    []SelfInclude
+	cycles          [][]string          // 每个循环 include 上的分类名，首尾相同
+	includedBy      map[string][]string // include 目标 → 声明该 include 的分类
+	baseNames       map[string]string   // 子目录中文件的基本名 → 相对路径名
+	foldedNames     map[string]string   // normalizeName 后的名称 → 原始名称，显示时始终使用原始大小写
+	aliases         map[string]string   // 已改名分类的旧名 → 新名，来自 -aliases 文件
+	classify        classifyConfig      // 节点分类使用的公司和国家列表
+}
+
+// defaultHTMLTitle HTML页面的默认标题
+const defaultHTMLTitle = "Domain List Community Tree"
+
+// htmlOptions HTML导出选项
+type htmlOptions struct {
+	Title string // 页面标题，为空时使用 defaultHTMLTitle
+
+	NoJS bool // 使用原生 <details>/<summary> 折叠，不依赖 JavaScript
+
+	// 以下功能依赖 JavaScript，NoJS 时不生效（统计面板除外）
+	Search      bool // 搜索框
+	ClassFilter bool // 按节点类型筛选
+	DarkMode    bool // 深色模式切换
+	Stats       bool // 统计面板
+	DeepLink    bool // 通过 #名称 直接定位节点
+}
+
+// defaultHTMLOptions 返回默认启用的页面功能
+func defaultHTMLOptions() htmlOptions {
+	return htmlOptions{Search: true, DarkMode: true, Stats: true}
+}
+
+// richHTMLOptions 返回启用全部页面功能的选项
+func richHTMLOptions() htmlOptions {
+	return htmlOptions{
+		Search:      true,
+		ClassFilter: true,
+		DarkMode:    true,
+		Stats:       true,
+		DeepLink:    true,
+	}
+}
+
+// htmlTemplateData 传给HTML模板的数据，自定义模板可以使用这些字段
+type htmlTemplateData struct {
+	Title           string
+	TreeHTML        template.HTML
+	TotalCategories int
+	UpdateAt        string
+	Version         string
+	Options         htmlOptions
+	Stats           htmlStats
+}
+
+// htmlStats HTML统计面板数据
+type htmlStats struct {
+	TotalCategories int
+	TopLevel        int
+	MaxDepth        int
+	IncludeEdges    int
+	Classes         []classCount // 各类型的分类数量，用作图例
 }
 
 // NewCategoryAnalyzer 创建新的分析器
 func NewCategoryAnalyzer(dataDir string) *CategoryAnalyzer {
 	return &CategoryAnalyzer{
 		dataDir:        dataDir,
+		maxFileSize:    defaultMaxFileSize,
+		retries:        defaultRetries,
+		commentMarkers: defaultCommentMarkers,
+		sourceRef:      defaultSourceRef,
+		sourceBase:     defaultSourceBase,
+		html:           defaultHTMLOptions(),
+		classify:       defaultClassifyConfig(),
 		categories:     make(map[string]*TreeNode),
-		tree:           &TreeNode{Name: "domain-list-community", Children: make(map[string]*TreeNode)},
+		tree:           &TreeNode{Name: defaultRootName, Children: make(map[string]*TreeNode)},
 		processedFiles: make(map[string]bool),
+		skippedFiles:   make(map[string]bool),
+		rules:          make(map[string][]Rule),
+		includedBy:     make(map[string][]string),
+		baseNames:      make(map[string]string),
+		foldedNames:    make(map[string]string),
 	}
 }
 
+// defaultRetries 克隆的默认尝试次数
+const defaultRetries = 3
+
+// v2rayRepoURL 数据目录缺失时默认克隆的仓库
+const v2rayRepoURL = "https://github.com/v2ray/domain-list-community.git"
+
+// DownloadV2RayRepoData 浅克隆 domain-list-community 仓库，并将其 data 目录复制到分析器的数据目录
+// branch 为空时使用仓库的默认分支
+func (ca *CategoryAnalyzer) DownloadV2RayRepoData(ctx context.Context, repoURL, branch string) error {
+	tmpDir, err := os.MkdirTemp("", "domain-list-community-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := ca.cloneWithRetry(ctx, repoURL, branch, tmpDir); err != nil {
+		return fmt.Errorf("克隆 %s 失败: %w", repoURL, err)
+	}
+	// 拿不到提交时只影响 -pin-commit auto，不影响数据本身
+	commit, err := cloneHead(tmpDir)
+	if err != nil {
+		logger.Printf("⚠️  无法读取克隆的 HEAD 提交: %v\n", err)
+	}
+
+	// 刷新过期数据时先清掉旧文件，避免上游已删除的分类残留
+	if err := os.RemoveAll(ca.dataDir); err != nil {
+		return err
+	}
+	if err := copyDir(filepath.Join(tmpDir, "data"), ca.dataDir); err != nil {
+		// 不保留复制了一半的目录，否则下次运行会把它当成完整数据
+		os.RemoveAll(ca.dataDir)
+		return fmt.Errorf("复制数据目录失败: %w", err)
+	}
+	return ca.writeDataCache(time.Now(), commit)
+}
+
+// retryBaseDelay 第一次重试前的等待时间，之后每次翻倍
+const retryBaseDelay = time.Second
+
+// cloneWithRetry 按 ca.retries 次数尝试克隆，失败后以指数退避等待并清空 dir 再试
+// 只有全部尝试都失败或 ctx 结束时才返回错误
+func (ca *CategoryAnalyzer) cloneWithRetry(ctx context.Context, repoURL, branch, dir string) error {
+	attempts := max(ca.retries, 1)
+	delay := retryBaseDelay
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = ca.cloneRepo(ctx, repoURL, branch, dir); err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if attempt == attempts {
+			break
+		}
+
+		logger.Printf("⚠️  第 %d/%d 次克隆失败: %v，%v 后重试\n", attempt, attempts, err, delay)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+		// git clone 要求目标目录为空，清掉上次失败留下的内容
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// dataCacheFile 记录上次成功下载的时间和提交的文件，位于数据目录内
+// 第一行为 RFC 3339 时间，第二行为克隆的 HEAD 提交（旧版本写入的缓存没有这一行）
+const dataCacheFile = ".geotree_cache"
+
+// writeDataCache 记录数据的下载时间和提交，commit 为空时只记录时间
+func (ca *CategoryAnalyzer) writeDataCache(t time.Time, commit string) error {
+	data := t.UTC().Format(time.RFC3339) + "\n"
+	if commit != "" {
+		data += commit + "\n"
+	}
+	return os.WriteFile(filepath.Join(ca.dataDir, dataCacheFile), []byte(data), 0644)
+}
+
+// readDataCache 返回缓存文件的各行，没有缓存记录时返回 nil
+func (ca *CategoryAnalyzer) readDataCache() []string {
+	data, err := os.ReadFile(filepath.Join(ca.dataDir, dataCacheFile))
+	if err != nil {
+		return nil
+	}
+	return strings.Fields(string(data))
+}
+
+// dataCacheTime 返回上次下载数据的时间
+// 没有缓存记录（如手动准备的数据目录）时 ok 为 false
+func (ca *CategoryAnalyzer) dataCacheTime() (t time.Time, ok bool) {
+	lines := ca.readDataCache()
+	if len(lines) == 0 {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, lines[0])
+	return t, err == nil
+}
+
+// dataCacheCommit 返回上次下载数据时克隆的提交
+// 手动准备的数据目录或旧版本写入的缓存没有记录提交，此时返回错误
+func (ca *CategoryAnalyzer) dataCacheCommit() (string, error) {
+	lines := ca.readDataCache()
+	if len(lines) < 2 {
+		return "", fmt.Errorf("%s 中没有记录下载的提交（数据不是自动下载的，或由旧版本下载）", ca.dataDir)
+	}
+	return lines[1], nil
+}
+
+// cloneHead 返回 dir 中克隆的仓库的 HEAD 提交，go-git 和 git 命令的克隆均可读取
+func cloneHead(dir string) (string, error) {
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return "", err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return "", err
+	}
+	return head.Hash().String(), nil
+}
+
+// cloneRepo 浅克隆仓库到 dir
+// 开启 useGoGit 时优先使用内置的 go-git，失败后再回退到 git 命令
+func (ca *CategoryAnalyzer) cloneRepo(ctx context.Context, repoURL, branch, dir string) error {
+	proxy := ca.cloneProxy(repoURL)
+	if ca.useGoGit {
+		opts := &git.CloneOptions{URL: repoURL, Depth: 1, Progress: logger.Writer()}
+		opts.ProxyOptions.URL = proxy
+		if branch != "" {
+			opts.ReferenceName = plumbing.NewBranchReferenceName(branch)
+			opts.SingleBranch = true
+		}
+		_, err := git.PlainCloneContext(ctx, dir, false, opts)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		logger.Printf("⚠️  go-git 克隆失败: %v，改用 git 命令\n", err)
+		// 清掉 go-git 留下的内容，git clone 要求目标目录为空
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+	}
+
+	args := []string{"clone", "--depth=1"}
+	if branch != "" {
+		args = append(args, "--branch", branch)
+	}
+	cmd := exec.CommandContext(ctx, "git", append(args, repoURL, dir)...)
+	cmd.Stdout = logger.Writer()
+	cmd.Stderr = os.Stderr
+	if ca.proxy != "" {
+		// git 命令本身读取代理环境变量，只需在指定 -proxy 时覆盖
+		cmd.Env = append(os.Environ(),
+			"http_proxy="+ca.proxy, "https_proxy="+ca.proxy,
+			"HTTP_PROXY="+ca.proxy, "HTTPS_PROXY="+ca.proxy)
+	}
+	return cmd.Run()
+}
+
+// cloneProxy 返回克隆 repoURL 使用的代理地址
+// 优先级：-proxy 参数 > HTTPS_PROXY/HTTP_PROXY 环境变量（大小写形式均可，遵守 NO_PROXY），都没有时不使用代理
+func (ca *CategoryAnalyzer) cloneProxy(repoURL string) string {
+	if ca.proxy != "" {
+		return ca.proxy
+	}
+	u, err := url.Parse(repoURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	proxy, err := http.ProxyFromEnvironment(&http.Request{URL: u})
+	if err != nil || proxy == nil {
+		return ""
+	}
+	return proxy.String()
+}
+
 func copyDir(src string, dst string) error {
 	entries, err := os.ReadDir(src)
 	if err != nil {
@@ -67,6 +407,43 @@ func copyDir(src string, dst string) error {
 	return nil
 }
 
+// logger 进度和状态信息的输出，写到标准错误，-quiet 时丢弃
+// 标准输出只用于树、报告和 JSON 等数据
+var logger = log.New(os.Stderr, "", 0)
+
+// realStdout 进程的标准输出；JSON 写到标准输出时 os.Stdout 被换成标准错误，进度信息不会混入 JSON
+var realStdout = os.Stdout
+
+// outputFileMode 所有导出文件使用的权限
+var outputFileMode os.FileMode = 0644
+
+// createOutputFile 创建导出文件并应用 outputFileMode
+// 创建后再 chmod 一次，使权限不受 umask 影响
+func createOutputFile(filename string) (*os.File, error) {
+	file, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, outputFileMode)
+	if err != nil {
+		return nil, err
+	}
+	if err := file.Chmod(outputFileMode); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// writeOutputFile 写入导出文件并应用 outputFileMode
+func writeOutputFile(filename string, data []byte) error {
+	file, err := createOutputFile(filename)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
 // copyFile 复制单个文件
 func copyFile(srcFile, dstFile string) error {
 	in, err := os.Open(srcFile)
@@ -79,14 +456,17 @@ func copyFile(srcFile, dstFile string) error {
 	if err != nil {
 		return err
 	}
-	defer out.Close()
 
-	_, err = io.Copy(out, in)
-	return err
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	// 写入错误可能直到 Close 才返回
+	return out.Close()
 }
 
 // ScanDataDirectory 扫描data目录
-func (ca *CategoryAnalyzer) ScanDataDirectory() error {
+func (ca *CategoryAnalyzer) ScanDataDirectory(ctx context.Context) error {
 	if _, err := os.Stat(ca.dataDir); os.IsNotExist(err) {
 		return fmt.Errorf("目录不存在: %s", ca.dataDir)
 	}
@@ -95,6 +475,13 @@ func (ca *CategoryAnalyzer) ScanDataDirectory() error {
 		if err != nil || d.IsDir() {
 			return err
 		}
+		// 跳过 .geotree_cache 等隐藏文件
+		if strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
 		relPath, _ := filepath.Rel(ca.dataDir, path)
 		filename := strings.ReplaceAll(relPath, string(filepath.Separator), "/")
@@ -102,6 +489,18 @@ func (ca *CategoryAnalyzer) ScanDataDirectory() error {
 
 		node := &TreeNode{Name: filename, Children: make(map[string]*TreeNode)}
 		ca.categories[filename] = node
+		// include 只写基本名，子目录中的文件额外按基本名索引；重名时保留遍历顺序中的第一个
+		if base := filename[strings.LastIndex(filename, "/")+1:]; base != filename {
+			if _, exists := ca.baseNames[base]; !exists {
+				ca.baseNames[base] = filename
+			}
+			if _, exists := ca.foldedNames[normalizeName(base)]; !exists {
+				ca.foldedNames[normalizeName(base)] = filename
+			}
+		}
+		if _, exists := ca.foldedNames[normalizeName(filename)]; !exists {
+			ca.foldedNames[normalizeName(filename)] = filename
+		}
 
 		return nil
 	})
@@ -109,77 +508,669 @@ func (ca *CategoryAnalyzer) ScanDataDirectory() error {
 	return err
 }
 
+// parsedFile 单个数据文件的解析结果
+type parsedFile struct {
+	includes   []string
+	rules      []Rule         // 文件中的全部规则
+	refs       []IncludeRef   // 每条 include 的行号和属性
+	counts     map[string]int // 各类型规则数量
+	ruleLines  []int          // 直接声明的规则所在行号
+	attributes map[string]int // 各 @ 属性出现次数
+
+	sourceLines []string // 文件原始内容，仅在开启 keepSourceLines 时读取
+}
+
+// Rule 数据文件中的一条规则
+type Rule struct {
+	Type   string // full / domain / keyword / regexp / include
+	Value  string // 去掉类型前缀和属性后的内容
+	Prefix string // 原文中的类型前缀（如 domain:），没有写前缀时为空
+	Line   int    // 所在行号
+
+	// Attributes 行内以 @ 开头的属性，如 @ads、@cn
+	Attributes []string
+}
+
 // parseIncludes 解析文件中的include关系
 func (ca *CategoryAnalyzer) parseIncludes(filepath string) ([]string, error) {
+	rules, err := ca.parseRules(filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	var includes []string
+	for _, rule := range rules {
+		if rule.Type == "include" {
+			includes = append(includes, rule.Value)
+		}
+	}
+	return includes, nil
+}
+
+// parseRules 解析文件中的全部规则
+// 注释和空行会被跳过，没有类型前缀的行视为 domain 规则
+func (ca *CategoryAnalyzer) parseRules(filepath string) ([]Rule, error) {
 	file, err := os.Open(filepath)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
 
-	var includes []string
+	if ca.maxFileSize > 0 {
+		info, err := file.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if info.Size() > ca.maxFileSize {
+			return nil, fmt.Errorf("文件过大 (%d 字节，上限 %d 字节): %s", info.Size(), ca.maxFileSize, filepath)
+		}
+	}
+
+	var rules []Rule
 	scanner := bufio.NewScanner(file)
+	lineNo := 0
 
 	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if strings.HasPrefix(line, "include:") {
-			includedFile := strings.TrimSpace(strings.TrimPrefix(line, "include:"))
-			includes = append(includes, includedFile)
+		lineNo++
+		line := ca.stripComment(scanner.Text())
+		ruleType := classifyRule(line)
+		if ruleType == "" {
+			continue
+		}
+		content, prefixed := strings.CutPrefix(line, ruleType+":")
+		rule := newRule(ruleType, content, lineNo)
+		if prefixed {
+			rule.Prefix = ruleType + ":"
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, scanner.Err()
+}
+
+// parseFile 解析文件，返回include关系和各类型规则的数量
+func (ca *CategoryAnalyzer) parseFile(filepath string) (*parsedFile, error) {
+	rules, err := ca.parseRules(filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := &parsedFile{rules: rules, counts: make(map[string]int), attributes: make(map[string]int)}
+	for _, rule := range rules {
+		for _, attr := range rule.Attributes {
+			parsed.attributes[attr]++
+		}
+		if rule.Type == "include" {
+			parsed.includes = append(parsed.includes, rule.Value)
+			parsed.refs = append(parsed.refs, IncludeRef{
+				Name: rule.Value,
+				Line: rule.Line,
+				Attr: strings.Join(rule.Attributes, " "),
+			})
+			continue
+		}
+		parsed.counts[rule.Type]++
+		parsed.ruleLines = append(parsed.ruleLines, rule.Line)
+	}
+
+	if ca.keepSourceLines {
+		// parseRules 已检查过文件大小，这里再次读取不会超过上限
+		data, err := os.ReadFile(filepath)
+		if err != nil {
+			return nil, err
+		}
+		parsed.sourceLines = strings.Split(strings.TrimRight(string(data), "\r\n"), "\n")
+	}
+
+	return parsed, nil
+}
+
+// newRule 拆分规则内容中的值和 @ 属性，字段之间可以是空格或制表符
+func newRule(ruleType, content string, line int) Rule {
+	rule := Rule{Type: ruleType, Line: line}
+	var values []string
+	for _, field := range strings.Fields(content) {
+		if strings.HasPrefix(field, "@") {
+			rule.Attributes = append(rule.Attributes, field)
+		} else {
+			values = append(values, field)
 		}
 	}
+	rule.Value = strings.Join(values, " ")
+	return rule
+}
 
-	return includes, scanner.Err()
+// stripComment 去掉行内注释并裁剪空白
+// 注释标记只有出现在行首或空白之后才生效，避免误伤 regexp 等规则中的字符
+func (ca *CategoryAnalyzer) stripComment(line string) string {
+	for _, marker := range ca.commentMarkers {
+		for offset := 0; offset < len(line); {
+			idx := strings.Index(line[offset:], marker)
+			if idx < 0 {
+				break
+			}
+			idx += offset
+			if idx == 0 || line[idx-1] == ' ' || line[idx-1] == '\t' {
+				line = line[:idx]
+				break
+			}
+			offset = idx + len(marker)
+		}
+	}
+	return strings.TrimSpace(line)
 }
 
-// BuildTree 构建树结构
-func (ca *CategoryAnalyzer) BuildTree() {
-	for categoryName := range ca.categories {
-		ca.processCategory(categoryName)
+// classifyRule 返回已去除注释的规则行类型，空行返回空字符串
+func classifyRule(line string) string {
+	if line == "" {
+		return ""
+	}
+
+	for _, ruleType := range ruleTypes {
+		if strings.HasPrefix(line, ruleType+":") {
+			return ruleType
+		}
+	}
+	if strings.HasPrefix(line, "include:") {
+		return "include"
+	}
+	// 没有前缀的行默认为 domain 规则
+	return "domain"
+}
+
+// BuildTree 构建树结构，ctx 被取消时中止并返回其错误
+// 先并发解析全部文件，再按名称顺序在内存中建立关系，结果与解析顺序无关
+func (ca *CategoryAnalyzer) BuildTree(ctx context.Context) error {
+	results, err := ca.parseAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(ca.categories))
+	for name := range ca.categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if ca.buildDepth > 0 {
+		if err := ca.processLimited(ctx, names, results); err != nil {
+			return err
+		}
+	} else if err := ca.processCategory(ctx, names, results); err != nil {
+		return err
+	}
+
+	ca.breakCycles()
+	if ca.buildDepth > 0 {
+		ca.trimTruncated()
+	}
+	ca.linkParents()
+	for _, cycle := range ca.cycles {
+		logger.Printf("⚠️  检测到循环 include: %s\n", strings.Join(cycle, " → "))
+	}
+	for _, m := range ca.MissingIncludes() {
+		logger.Printf("⚠️  无法解析的 include: %s → %s\n", m.Source, m.Target)
 	}
 
 	for _, node := range ca.categories {
-		if node.Parent == nil {
+		if len(node.Parents) == 0 {
 			ca.tree.Children[node.Name] = node
 		}
 	}
+	return nil
 }
 
-// processCategory 处理分类的包含关系
-func (ca *CategoryAnalyzer) processCategory(categoryName string) {
-	if ca.processedFiles[categoryName] {
-		return
+// processLimited 按 ca.buildDepth 构建 include 图
+// 从没有被任何分类 include 的分类出发按层处理，节点深度取最短路径，位于限制深度的节点不再展开，
+// 只能在限制深度之下到达的分类不会被处理，最后从 ca.categories 中删除
+func (ca *CategoryAnalyzer) processLimited(ctx context.Context, names []string, results map[string]parseResult) error {
+	targets := ca.includeTargets(results)
+	included := make(map[string]bool)
+	for _, children := range targets {
+		for _, child := range children {
+			included[child] = true
+		}
+	}
+	var roots []string
+	for _, name := range names {
+		if !included[name] {
+			roots = append(roots, name)
+		}
+	}
+
+	// 只出现在循环中的分类从任何根都到达不了，按名称顺序从第一个分类开始各算一支，与 breakCycles 选出的顶级分类一致
+	reachable := make(map[string]bool)
+	markReachable(targets, roots, reachable)
+	if err := ca.processCategory(ctx, roots, results); err != nil {
+		return err
+	}
+	for _, name := range names {
+		if reachable[name] {
+			continue
+		}
+		markReachable(targets, []string{name}, reachable)
+		if err := ca.processCategory(ctx, []string{name}, results); err != nil {
+			return err
+		}
+	}
+
+	for name := range ca.categories {
+		if !ca.processedFiles[name] {
+			delete(ca.categories, name)
+		}
+	}
+	return nil
+}
+
+// trimTruncated 清空限制深度处节点的子节点，只剩回边的节点在断开循环后没有子节点，不算被截断
+func (ca *CategoryAnalyzer) trimTruncated() {
+	for _, node := range ca.categories {
+		if !node.Truncated {
+			continue
+		}
+		node.Truncated = len(node.Children) > 0
+		node.Children = make(map[string]*TreeNode)
+		node.ChildAttributes = nil
+	}
+}
+
+// includeTargets 返回每个分类解析后的 include 目标，只包含存在的分类，不包含自身
+func (ca *CategoryAnalyzer) includeTargets(results map[string]parseResult) map[string][]string {
+	targets := make(map[string][]string, len(results))
+	for name, result := range results {
+		if result.err != nil {
+			continue
+		}
+		for _, target := range result.parsed.includes {
+			target = ca.resolveInclude(target)
+			if _, exists := ca.categories[target]; exists && target != name {
+				targets[name] = append(targets[name], target)
+			}
+		}
+	}
+	return targets
+}
+
+// markReachable 将从 starts 出发沿 targets 能到达的分类（包括 starts 本身）加入 reachable
+func markReachable(targets map[string][]string, starts []string, reachable map[string]bool) {
+	stack := append([]string(nil), starts...)
+	for len(stack) > 0 {
+		name := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if reachable[name] {
+			continue
+		}
+		reachable[name] = true
+		stack = append(stack, targets[name]...)
+	}
+}
+
+// breakCycles 按名称顺序深度优先遍历 include 关系，记录并断开所有回边
+// 不断开的话循环上的节点都有父节点，会从树中整体消失
+func (ca *CategoryAnalyzer) breakCycles() {
+	// 颜色标记：white 未访问，gray 在当前路径上，black 已处理完
+	const (
+		white = iota
+		gray
+		black
+	)
+	color := make(map[*TreeNode]int)
+
+	// 使用显式栈代替递归，与 processCategory 一样避免超长的 include 链导致栈溢出
+	// 栈中的节点即当前路径，next 为下一个要检查的子节点下标
+	type frame struct {
+		node     *TreeNode
+		children []string
+		next     int
+	}
+	var stack []frame
+	var path []*TreeNode
+	push := func(node *TreeNode) {
+		color[node] = gray
+		stack = append(stack, frame{node: node, children: sortedChildNames(node)})
+		path = append(path, node)
+	}
+
+	names := make([]string, 0, len(ca.categories))
+	for name := range ca.categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if color[ca.categories[name]] != white {
+			continue
+		}
+		push(ca.categories[name])
+		for len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			if top.next == len(top.children) {
+				color[top.node] = black
+				stack = stack[:len(stack)-1]
+				path = path[:len(path)-1]
+				continue
+			}
+
+			childName := top.children[top.next]
+			top.next++
+			child := top.node.Children[childName]
+			switch color[child] {
+			case gray:
+				ca.cycles = append(ca.cycles, cyclePath(path, child))
+				delete(top.node.Children, childName)
+				delete(top.node.ChildAttributes, childName)
+			case white:
+				push(child)
+			}
+		}
+	}
+}
+
+// linkParents 根据 include 关系为每个节点填充 Parents
+// 同一个分类被多个文件包含时，它会出现在每个父节点之下
+func (ca *CategoryAnalyzer) linkParents() {
+	names := make([]string, 0, len(ca.categories))
+	for name, node := range ca.categories {
+		names = append(names, name)
+		node.Parents = nil
 	}
-	ca.processedFiles[categoryName] = true
+	sort.Strings(names)
 
-	node := ca.categories[categoryName]
-	includes, err := ca.getCategoryIncludes(categoryName)
+	for _, name := range names {
+		node := ca.categories[name]
+		for _, child := range node.Children {
+			child.Parents = append(child.Parents, node)
+		}
+	}
+}
+
+// cyclePath 从当前路径中截取以 target 开始的循环，并在末尾补上 target
+func cyclePath(path []*TreeNode, target *TreeNode) []string {
+	var cycle []string
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i] == target {
+			for _, n := range path[i:] {
+				cycle = append(cycle, n.Name)
+			}
+			break
+		}
+	}
+	return append(cycle, target.Name)
+}
+
+// sortedChildNames 返回按名称排序的子节点名
+func sortedChildNames(node *TreeNode) []string {
+	names := make([]string, 0, len(node.Children))
+	for name := range node.Children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseResult 单个文件的解析结果
+type parseResult struct {
+	parsed *parsedFile
+	err    error
+}
+
+// parseAll 使用 runtime.NumCPU() 个 worker 并发解析所有分类文件
+func (ca *CategoryAnalyzer) parseAll(ctx context.Context) (map[string]parseResult, error) {
+	names := make(chan string)
+	type namedResult struct {
+		name string
+		parseResult
+	}
+	out := make(chan namedResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range names {
+				parsed, err := ca.getCategoryIncludes(name)
+				out <- namedResult{name, parseResult{parsed, err}}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(names)
+		for name := range ca.categories {
+			select {
+			case names <- name:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	results := make(map[string]parseResult, len(ca.categories))
+	for r := range out {
+		results[r.name] = r.parseResult
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// buildItem processCategory 队列中的分类及其深度（起点为 1）
+type buildItem struct {
+	name  string
+	depth int
+}
+
+// processCategory 从 starts 出发按层处理分类的包含关系，已处理过的分类跳过
+// 使用显式队列代替递归，避免超长的 include 链导致栈溢出；开启 ca.buildDepth 时位于限制深度的分类
+// 标记为 Truncated，它们的子分类不再继续处理
+func (ca *CategoryAnalyzer) processCategory(ctx context.Context, starts []string, results map[string]parseResult) error {
+	queue := make([]buildItem, 0, len(starts))
+	for _, name := range starts {
+		queue = append(queue, buildItem{name, 1})
+	}
+
+	for head := 0; head < len(queue); head++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		name, depth := queue[head].name, queue[head].depth
+		if ca.processedFiles[name] {
+			continue
+		}
+		ca.processedFiles[name] = true
+		atLimit := ca.buildDepth > 0 && depth >= ca.buildDepth
+
+		node := ca.categories[name]
+		parsed, err := results[name].parsed, results[name].err
+		if err != nil {
+			logger.Printf("⚠️  跳过 %s: %v\n", name, err)
+			ca.skippedFiles[name] = true
+			continue
+		}
+		ca.rules[name] = parsed.rules
+		node.RuleCounts = parsed.counts
+		node.RuleCount = len(parsed.ruleLines)
+		node.RuleLines = parsed.ruleLines
+		node.Attributes = parsed.attributes
+		node.SourceLines = parsed.sourceLines
+		if ca.provenance {
+			node.Source = filepath.ToSlash(filepath.Join(ca.dataDir, name))
+			node.Includes = parsed.refs
+		}
+
+		for i, includedFile := range parsed.includes {
+			// 包含自身必然是错误（包括大小写或别名不同的写法），单独记录且不建立父子关系
+			includedFile = ca.resolveInclude(includedFile)
+			if includedFile == name {
+				ca.selfIncludes = append(ca.selfIncludes, SelfInclude{Category: name, Line: parsed.refs[i].Line})
+				continue
+			}
+			ca.includedBy[includedFile] = append(ca.includedBy[includedFile], name)
+			if childNode, exists := ca.categories[includedFile]; exists {
+				// 限制深度处先照常建立子节点，断开循环后再由 trimTruncated 清空
+				node.Truncated = node.Truncated || atLimit
+				node.Children[includedFile] = childNode
+				if attrs := strings.Fields(parsed.refs[i].Attr); len(attrs) > 0 {
+					if node.ChildAttributes == nil {
+						node.ChildAttributes = make(map[string][]string)
+					}
+					node.ChildAttributes[includedFile] = attrs
+				}
+				if !atLimit {
+					queue = append(queue, buildItem{includedFile, depth + 1})
+				}
+			} else {
+				ca.missingIncludes = append(ca.missingIncludes, MissingInclude{Source: name, Target: includedFile})
+			}
+		}
+	}
+	return nil
+}
+
+// resolveInclude 将 include 目标解析为 ca.categories 的键
+// 优先匹配完整的相对路径名，其次匹配子目录中文件的基本名，都不存在时再查别名表
+func (ca *CategoryAnalyzer) resolveInclude(target string) string {
+	if name, ok := ca.lookupCategory(target); ok {
+		return name
+	}
+	if alias, exists := ca.aliases[target]; exists {
+		if name, ok := ca.lookupCategory(alias); ok {
+			return name
+		}
+	}
+	return target
+}
+
+// lookupCategory 按完整名称或子目录中文件的基本名查找分类
+// 都不匹配时忽略大小写和首尾空白再查一次，返回的名称保留文件名原本的大小写
+func (ca *CategoryAnalyzer) lookupCategory(target string) (string, bool) {
+	if _, exists := ca.categories[target]; exists {
+		return target, true
+	}
+	if name, exists := ca.baseNames[target]; exists {
+		return name, true
+	}
+	name, exists := ca.foldedNames[normalizeName(target)]
+	return name, exists
+}
+
+// normalizeName 返回用于不区分大小写比较的分类名
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// loadAliases 读取别名文件，每行一条 旧名=新名，空行和 # 开头的行被忽略
+func loadAliases(filename string) (map[string]string, error) {
+	file, err := os.Open(filename)
 	if err != nil {
-		return
+		return nil, err
 	}
+	defer file.Close()
 
-	for _, includedFile := range includes {
-		if childNode, exists := ca.categories[includedFile]; exists {
-			childNode.Parent = node
-			node.Children[includedFile] = childNode
-			ca.processCategory(includedFile)
+	aliases := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
 		}
+		old, new, ok := strings.Cut(line, "=")
+		old, new = strings.TrimSpace(old), strings.TrimSpace(new)
+		if !ok || old == "" || new == "" {
+			return nil, fmt.Errorf("%s 第 %d 行格式应为 旧名=新名: %q", filename, lineNo, line)
+		}
+		aliases[old] = new
 	}
+	return aliases, scanner.Err()
 }
 
 // getCategoryIncludes 获取分类的包含关系
-func (ca *CategoryAnalyzer) getCategoryIncludes(categoryName string) ([]string, error) {
+func (ca *CategoryAnalyzer) getCategoryIncludes(categoryName string) (*parsedFile, error) {
 	filepath := filepath.Join(ca.dataDir, categoryName)
-	return ca.parseIncludes(filepath)
+	return ca.parseFile(filepath)
+}
+
+// AttributesInUse 返回分类文件中出现过的 @ 属性（已排序）
+func (n *TreeNode) AttributesInUse() []string {
+	attrs := make([]string, 0, len(n.Attributes))
+	for attr := range n.Attributes {
+		attrs = append(attrs, attr)
+	}
+	sort.Strings(attrs)
+	return attrs
+}
+
+// aggregateRuleCounts 汇总节点及其所有后代的规则数量，共享的子节点只计一次
+func (ca *CategoryAnalyzer) aggregateRuleCounts(node *TreeNode) map[string]int {
+	totals := make(map[string]int)
+	visited := make(map[*TreeNode]bool)
+
+	var walk func(n *TreeNode)
+	walk = func(n *TreeNode) {
+		if visited[n] {
+			return
+		}
+		visited[n] = true
+		for ruleType, count := range n.RuleCounts {
+			totals[ruleType] += count
+		}
+		for _, child := range n.Children {
+			walk(child)
+		}
+	}
+	walk(node)
+
+	return totals
+}
+
+// TotalRules 返回节点及其所有后代的规则总数，共享的子节点只计一次
+func (ca *CategoryAnalyzer) TotalRules(node *TreeNode) int {
+	total := 0
+	for _, count := range ca.aggregateRuleCounts(node) {
+		total += count
+	}
+	return total
+}
+
+// truncatedAt 判断位于第 depth 层（顶级为 1）的节点是否因 renderDepth 而隐藏子节点
+func (ca *CategoryAnalyzer) truncatedAt(node *TreeNode, depth int) bool {
+	return ca.renderDepth > 0 && depth >= ca.renderDepth && len(node.Children) > 0
 }
 
 // PrintConsoleTree 打印控制台树结构
 func (ca *CategoryAnalyzer) PrintConsoleTree() {
 	fmt.Println("=== 控制台树形结构 ===")
-	ca.printNode(ca.tree, -1, true)
+	ca.renderTree(os.Stdout, ca.consoleColor)
+	fmt.Println()
+	ca.PrintClassLegend()
+}
+
+// ExportText 将与控制台相同的树形结构（不着色）写入文件，便于提交和逐周对比
+func (ca *CategoryAnalyzer) ExportText(filename string) error {
+	var b strings.Builder
+	ca.renderTree(&b, false)
+	if err := writeOutputFile(filename, []byte(b.String())); err != nil {
+		return err
+	}
+
+	logger.Printf("✅ 文本树已保存: %s\n", filename)
+	return nil
 }
 
-// printNode 打印节点
-func (ca *CategoryAnalyzer) printNode(node *TreeNode, depth int, isLast bool) {
+// renderTree 将整棵树（不含虚拟根节点）以制表符树形式写入 w，color 为 true 时按节点类型着色
+func (ca *CategoryAnalyzer) renderTree(w io.Writer, color bool) {
+	ca.writeNode(w, ca.tree, -1, true, color)
+}
+
+// writeNode 将节点及其子树写入 w，depth 为 -1 时不输出节点自身
+func (ca *CategoryAnalyzer) writeNode(w io.Writer, node *TreeNode, depth int, isLast bool, color bool) {
 	if depth >= 0 {
 		prefix := ""
 		for i := 0; i < depth; i++ {
@@ -192,371 +1183,503 @@ func (ca *CategoryAnalyzer) printNode(node *TreeNode, depth int, isLast bool) {
 			prefix += "├── "
 		}
 
-		fmt.Printf("%s%s\n", prefix, node.Name)
-	}
-
-	var childNames []string
-	for name := range node.Children {
-		childNames = append(childNames, name)
+		label := node.Name
+		if node.Context {
+			label = "(" + label + ")"
+		}
+		if ca.consoleCounts {
+			label += fmt.Sprintf(" (%d)", ca.TotalRules(node))
+		}
+		if color {
+			class := ca.getNodeClass(node.Name)
+			label = ansiColor(nodeClassColors[class], class == "category") + label + ansiReset
+		}
+		if ca.truncatedAt(node, depth+1) {
+			fmt.Fprintf(w, "%s%s … (+%d)\n", prefix, label, countDescendants(node))
+			return
+		}
+		fmt.Fprintf(w, "%s%s\n", prefix, label)
 	}
-	sort.Strings(childNames)
 
+	childNames := sortedChildNames(node)
 	for i, name := range childNames {
 		isLastChild := (i == len(childNames)-1)
-		ca.printNode(node.Children[name], depth+1, isLastChild)
+		ca.writeNode(w, node.Children[name], depth+1, isLastChild, color)
+	}
+}
+
+// jsonNode ExportJSON 输出的节点，children 为按名称排序的数组
+type jsonNode struct {
+	Name       string       `json:"name" yaml:"name"`
+	Depth      int          `json:"depth" yaml:"depth"`
+	RuleCount  int          `json:"rule_count" yaml:"rule_count"`
+	Attributes []string     `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+	Source     string       `json:"source,omitempty" yaml:"source,omitempty"`
+	Includes   []IncludeRef `json:"includes,omitempty" yaml:"includes,omitempty"`
+
+	// Truncated 节点的 include 因 -build-depth 没有被构建
+	Truncated bool `json:"truncated,omitempty" yaml:"truncated,omitempty"`
+
+	// IncludeAttributes 父分类 include 该节点时附带的属性选择器
+	IncludeAttributes []string `json:"include_attributes,omitempty" yaml:"include_attributes,omitempty"`
+
+	Children []*jsonNode `json:"children,omitempty" yaml:"children,omitempty"`
+}
+
+// newJSONNode 将节点及其子树转换为 jsonNode，depth 为节点所在层（根为 0）
+func newJSONNode(node *TreeNode, depth int) *jsonNode {
+	n := &jsonNode{
+		Name:       node.Name,
+		Depth:      depth,
+		RuleCount:  node.RuleCount,
+		Attributes: node.AttributesInUse(),
+		Source:     node.Source,
+		Includes:   node.Includes,
+		Truncated:  node.Truncated,
+	}
+	if len(n.Attributes) == 0 {
+		n.Attributes = nil
+	}
+	for _, name := range sortedChildNames(node) {
+		child := newJSONNode(node.Children[name], depth+1)
+		child.IncludeAttributes = node.ChildAttributes[name]
+		n.Children = append(n.Children, child)
+	}
+	return n
+}
+
+// legacyJSONNode -json-legacy 输出的节点，children 为以名称为键的映射
+type legacyJSONNode struct {
+	Name     string                     `json:"name"`
+	Children map[string]*legacyJSONNode `json:"children,omitempty"`
+	Source   string                     `json:"source,omitempty"`
+	Includes []IncludeRef               `json:"includes,omitempty"`
+}
+
+// newLegacyJSONNode 将节点及其子树转换为 legacyJSONNode
+func newLegacyJSONNode(node *TreeNode) *legacyJSONNode {
+	n := &legacyJSONNode{
+		Name:     node.Name,
+		Children: make(map[string]*legacyJSONNode, len(node.Children)),
+		Source:   node.Source,
+		Includes: node.Includes,
+	}
+	for name, child := range node.Children {
+		n.Children[name] = newLegacyJSONNode(child)
+	}
+	return n
+}
+
+// UnmarshalJSON 读取 ExportJSON 生成的节点
+// children 可以是数组（默认格式）或以名称为键的映射（-json-legacy 格式）
+func (n *TreeNode) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Name       string          `json:"name"`
+		RuleCount  int             `json:"rule_count"`
+		Attributes []string        `json:"attributes"`
+		Source     string          `json:"source"`
+		Includes   []IncludeRef    `json:"includes"`
+		Children   json.RawMessage `json:"children"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*n = TreeNode{
+		Name:      raw.Name,
+		Children:  make(map[string]*TreeNode),
+		RuleCount: raw.RuleCount,
+		Source:    raw.Source,
+		Includes:  raw.Includes,
+	}
+	if len(raw.Attributes) > 0 {
+		n.Attributes = make(map[string]int, len(raw.Attributes))
+		for _, attr := range raw.Attributes {
+			n.Attributes[attr]++
+		}
+	}
+
+	children := bytes.TrimSpace(raw.Children)
+	switch {
+	case len(children) == 0 || bytes.Equal(children, []byte("null")):
+	case children[0] == '[':
+		var list []*TreeNode
+		if err := json.Unmarshal(children, &list); err != nil {
+			return err
+		}
+		for _, child := range list {
+			n.Children[child.Name] = child
+		}
+	default:
+		if err := json.Unmarshal(children, &n.Children); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 // ExportJSON 导出为JSON格式
+// 默认每个节点包含 depth、rule_count、attributes 和有序的 children 数组；jsonLegacy 时输出旧的 name/children 映射格式
 func (ca *CategoryAnalyzer) ExportJSON(filename string) error {
-	jsonData, err := json.MarshalIndent(ca.tree, "", "  ")
+	if filename == "-" {
+		return ca.RenderJSON(realStdout)
+	}
+
+	file, err := createOutputFile(filename)
 	if err != nil {
 		return err
 	}
+	// 文件内容保持与 MarshalIndent 相同，不带 Encoder 追加的末尾换行
+	if err := ca.RenderJSON(&trimNewlineWriter{w: file}); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
 
-	err = os.WriteFile(filename, jsonData, 0644)
-	if err != nil {
+	logger.Printf("✅ JSON文件已保存: %s\n", filename)
+	return nil
+}
+
+// RenderJSON 将 ExportJSON 的内容以流式编码写入 w，末尾带换行
+func (ca *CategoryAnalyzer) RenderJSON(w io.Writer) error {
+	var v interface{} = newJSONNode(ca.tree, 0)
+	if ca.jsonLegacy {
+		v = newLegacyJSONNode(ca.tree)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// trimNewlineWriter 丢弃写入内容最末尾的一个换行，其余内容原样转发
+type trimNewlineWriter struct {
+	w       io.Writer
+	pending bool // 上一次写入以换行结尾，尚未转发
+}
+
+// Write 实现 io.Writer
+func (t *trimNewlineWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if n == 0 {
+		return 0, nil
+	}
+	if t.pending {
+		if _, err := t.w.Write([]byte("\n")); err != nil {
+			return 0, err
+		}
+	}
+	t.pending = p[n-1] == '\n'
+	if t.pending {
+		p = p[:n-1]
+	}
+	if _, err := t.w.Write(p); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// ExportYAML 导出与 JSON 相同结构的 YAML，children 为按名称排序的序列
+func (ca *CategoryAnalyzer) ExportYAML(filename string) error {
+	var b bytes.Buffer
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(newJSONNode(ca.tree, 0)); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
 		return err
 	}
 
-	fmt.Printf("✅ JSON文件已保存: %s\n", filename)
+	if err := writeOutputFile(filename, b.Bytes()); err != nil {
+		return err
+	}
+
+	logger.Printf("✅ YAML文件已保存: %s\n", filename)
 	return nil
 }
 
 // ExportHTML 导出为交互式HTML页面
 func (ca *CategoryAnalyzer) ExportHTML(filename string) error {
-	htmlTemplate := `<!DOCTYPE html>
-<html lang="zh-CN">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Domain List Community Tree</title>
-    <style>
-        body {
-            font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif;
-            margin: 20px;
-            background-color: #f5f5f5;
-            color: #333;
-        }
-
-        body {
-            font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif;
-            margin: 20px;
-            background-color: var(--bg-color);
-            color: var(--text-color);
-            transition: background-color 0.3s ease, color 0.3s ease;
-        }
-        .container {
-            max-width: 1200px;
-            margin: 0 auto;
-            background: white;
-            padding: 20px;
-            border-radius: 8px;
-            box-shadow: 0 2px 10px rgba(0,0,0,0.1);
-        }
-        .tree {
-            font-family: 'Courier New', monospace;
-            line-height: 1.6;
-            margin-top: 20px;
-        }
-        .node {
-            margin: 2px 0;
-            cursor: pointer;
-            transition: background-color 0.2s;
-            padding: 2px 4px;
-            display: flex;
-            align-items: center;
-            justify-content: space-between;
-        }
-        .node:hover {
-            background-color: #e3f2fd;
-            border-radius: 4px;
-        }
-        .node-content {
-            flex: 1;
-            cursor: pointer;
-        }
-        .node.collapsible .node-content:hover {
-            text-decoration: underline;
-        }
-        .view-source-btn {
-            padding: 2px 8px;
-            background: #28a745;
-            color: white;
-            border: none;
-            border-radius: 3px;
-            font-size: 11px;
-            cursor: pointer;
-            margin-left: 10px;
-            text-decoration: none;
-            display: inline-block;
-            opacity: 0.7;
-            transition: opacity 0.2s ease;
-        }
-        .view-source-btn:hover {
-            opacity: 1;
-            background: #218838;
-        }
-        .node.category { color: #7b1fa2; font-weight: bold; }
-        .node.company { color: #2e7d32; }
-        .node.geo { color: #f57c00; }
-        .node.service { color: #1976d2; }
-        .collapsible {
-            position: relative;
-        }
-        .collapsible:before {
-            content: '▼';
-            position: absolute;
-            left: -15px;
-            color: #666;
-            font-size: 10px;
-        }
-        .collapsible.collapsed:before {
-            content: '▶';
-        }
-        .children {
-            margin-left: 20px;
-        }
-        .children.hidden {
-            display: none;
-        }
-        .header {
-            text-align: center;
-            margin-bottom: 30px;
-        }
-        .stats {
-            background: #e3f2fd;
-            padding: 15px;
-            border-radius: 6px;
-            margin-bottom: 20px;
-        }
-        .controls {
-            display: flex;
-            gap: 10px;
-            justify-content: center;
-            margin-bottom: 20px;
-            flex-wrap: wrap;
-        }
-        .btn {
-            padding: 10px 20px;
-            background: #007bff;
-            color: white;
-            border: none;
-            border-radius: 5px;
-            cursor: pointer;
-            font-size: 14px;
-            transition: background-color 0.2s ease;
-        }
-        .btn:hover {
-            background: #0056b3;
-        }
-        .btn:active {
-            transform: translateY(1px);
-        }
-        @media (max-width: 768px) {
-            body {
-                margin: 10px;
-            }
-            .container {
-                padding: 15px;
-            }
-            .controls {
-                flex-direction: column;
-                align-items: center;
-            }
-            .btn {
-                width: 100%;
-                max-width: 200px;
-            }
-        }
-    </style>
-</head>
-<body>
-    <div class="container">
-        <div class="header">
-            <h1>🌳 Domain List Community Tree</h1>
-			<p>更新时间：{{.UpdateAt}} | 每周更新1次</p>
-        </div>
-        
-        <div class="controls">
-            <button class="btn" id="expandAllBtn">📂 展开全部</button>
-            <button class="btn" id="collapseAllBtn">📁 收起全部</button>
-        </div>
-        
-        <div class="tree" id="tree">
-            {{.TreeHTML}}
-        </div>
-    </div>
-
-    <script>
-        // 折叠/展开功能
-        document.addEventListener('click', function(e) {
-            // 如果点击的是源码按钮，不执行展开/收起逻辑
-            if (e.target.classList.contains('view-source-btn')) {
-                return;
-            }
-            
-            // 查找最近的可折叠节点
-            let targetNode = e.target;
-            
-            // 如果点击的是node-content，找到它的父节点
-            if (e.target.classList.contains('node-content')) {
-                targetNode = e.target.parentElement;
-            }
-            
-            // 如果点击的节点本身就是collapsible，或者它的父节点是collapsible
-            if (targetNode.classList.contains('collapsible')) {
-                e.preventDefault();
-                e.stopPropagation();
-                
-                targetNode.classList.toggle('collapsed');
-                const children = targetNode.nextElementSibling;
-                if (children && children.classList.contains('children')) {
-                    children.classList.toggle('hidden');
-                }
-            }
-        });
-
-        // 展开全部功能
-        document.getElementById('expandAllBtn').addEventListener('click', function() {
-            const collapsibleNodes = document.querySelectorAll('.collapsible');
-            const childrenNodes = document.querySelectorAll('.children');
-            
-            collapsibleNodes.forEach(node => {
-                node.classList.remove('collapsed');
-            });
-            
-            childrenNodes.forEach(children => {
-                children.classList.remove('hidden');
-            });
-        });
-
-        // 收起全部功能 - 只保留一级分类
-        document.getElementById('collapseAllBtn').addEventListener('click', function() {
-            // 收起所有节点
-            const allCollapsibleNodes = document.querySelectorAll('.collapsible');
-            const allChildrenNodes = document.querySelectorAll('.children');
-            
-            allCollapsibleNodes.forEach(node => {
-                node.classList.add('collapsed');
-            });
-            
-            allChildrenNodes.forEach(children => {
-                children.classList.add('hidden');
-            });
-        });
-
-        // 初始化：展开第一层
-        document.querySelectorAll('.tree > .node.collapsible').forEach(node => {
-            node.classList.remove('collapsed');
-            const children = node.nextElementSibling;
-            if (children) children.classList.remove('hidden');
-        });
-    </script>
-</body>
-</html>`
-
-	treeHTML := ca.generateHTMLTree(ca.tree, 0)
-	totalCategories := len(ca.categories)
-
-	tmpl, err := template.New("html").Parse(htmlTemplate)
+	file, err := createOutputFile(filename)
 	if err != nil {
 		return err
 	}
 
-	file, err := os.Create(filename)
+	if err := ca.RenderHTML(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	logger.Printf("✅ HTML文件已保存: %s\n", filename)
+	logger.Printf("   在浏览器中打开查看交互式树结构\n")
+	return nil
+}
+
+// RenderHTML 将交互式HTML页面写入 w
+func (ca *CategoryAnalyzer) RenderHTML(w io.Writer) error {
+	tmpl, err := parseHTMLTemplate(ca.htmlTemplateName, ca.htmlTemplate)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 
-	loc, _ := time.LoadLocation("Asia/Shanghai") // 东八区时区对象
-	now := time.Now().In(loc)                    // 转换为东八区时间
+	treeHTML := ca.generateHTMLTree(ca.tree, nil)
+	totalCategories := len(ca.categories)
+	stats := ca.computeHTMLStats()
+	title := ca.html.Title
+	if title == "" {
+		title = defaultHTMLTitle
+	}
 
-	err = tmpl.Execute(file, struct {
-		TreeHTML        template.HTML
-		TotalCategories int
-		UpdateAt        string
-	}{
+	loc := ca.location
+	if loc == nil {
+		loc = loadTimezone(defaultTimezone)
+	}
+	now := time.Now().In(loc)
+
+	return tmpl.Execute(w, htmlTemplateData{
+		Title:           title,
 		TreeHTML:        template.HTML(treeHTML),
 		TotalCategories: totalCategories,
 		UpdateAt:        now.Format("2006-01-02 15:04:05"),
+		Version:         versionString(),
+		Options:         ca.html,
+		Stats:           stats,
 	})
+}
+
+// defaultTimezone HTML 更新时间的默认时区
+const defaultTimezone = "Asia/Shanghai"
 
+// loadTimezone 按 IANA 名称加载时区，系统缺少 tzdata 或名称无效时警告并改用 UTC
+func loadTimezone(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
 	if err != nil {
-		return err
+		logger.Printf("⚠️  无法加载时区 %s: %v，改用 UTC\n", name, err)
+		return time.UTC
 	}
+	return loc
+}
 
-	fmt.Printf("✅ HTML文件已保存: %s\n", filename)
-	fmt.Printf("   在浏览器中打开查看交互式树结构\n")
-	return nil
+// computeHTMLStats 计算统计面板数据
+func (ca *CategoryAnalyzer) computeHTMLStats() htmlStats {
+	tree := ca.Stats()
+	return htmlStats{
+		TotalCategories: tree.Categories,
+		TopLevel:        tree.Roots,
+		MaxDepth:        tree.MaxDepth,
+		IncludeEdges:    tree.IncludeEdges,
+		Classes:         ca.orderedClassCounts(),
+	}
 }
 
 // generateHTMLTree 生成HTML树结构
-func (ca *CategoryAnalyzer) generateHTMLTree(node *TreeNode, depth int) string {
+// path 为从顶级分类到该节点的渲染路径（虚拟根为空），节点深度即 len(path)
+func (ca *CategoryAnalyzer) generateHTMLTree(node *TreeNode, path []string) string {
 	var sb strings.Builder
+	depth := len(path)
 
-	if node.Name != "domain-list-community" {
-		class := ca.getNodeClass(node.Name)
-		hasChildren := len(node.Children) > 0
+	if node != ca.tree {
+		nodeClass := ca.getNodeClass(node.Name)
+		class := nodeClass
+		if node.Context {
+			class += " context"
+		}
+		dataName := template.HTMLEscapeString(node.Name)
+		dataPath := template.HTMLEscapeString(strings.Join(path, " > "))
+		dataAttrs := fmt.Sprintf(`data-name="%s" data-depth="%d" data-class="%s" data-path="%s"`, dataName, depth, nodeClass, dataPath)
+
+		// 构建节点内容，悬停时显示完整路径
+		nodeContent := fmt.Sprintf(`<span class="node-content" title="%s">%s</span>`, dataPath, dataName)
+		if node.RuleCount > 0 {
+			nodeContent += fmt.Sprintf(`<span class="rule-badge" title="直接声明的规则数">%d</span>`, node.RuleCount)
+		}
 
-		// 构建节点内容
-		nodeContent := fmt.Sprintf(`<span class="node-content">%s</span>`, node.Name)
+		// 超过 renderDepth 的子树不输出，只显示隐藏的节点数
+		truncated := ca.truncatedAt(node, depth)
+		if truncated {
+			nodeContent += fmt.Sprintf(`<span class="truncated">… (+%d)</span>`, countDescendants(node))
+		}
+		hasChildren := len(node.Children) > 0 && !truncated
 
-		// 添加查看源码按钮
-		sourceButton := fmt.Sprintf(`<a href="https://raw.githubusercontent.com/v2ray/domain-list-community/refs/heads/master/data/%s" target="_blank" class="view-source-btn" onclick="event.stopPropagation()">Github Source</a>`, node.Name)
+		// 添加查看源码按钮，分类名和 -source-base 中可能出现 & " 等字符
+		sourceURL := template.HTMLEscapeString(ca.sourceURL(node.Name))
+		sourceButton := fmt.Sprintf(`<a href="%s" target="_blank" class="view-source-btn" onclick="event.stopPropagation()">Github Source</a>`, sourceURL)
+		if !ca.html.NoJS {
+			sourceButton += fmt.Sprintf(`<button type="button" class="copy-url-btn" data-url="%s">复制链接</button>`, sourceURL)
+		}
+		if len(node.SourceLines) > 0 {
+			// 离线时 Github Source 链接不可用，直接内嵌源文件内容
+			sourceButton += fmt.Sprintf(`<details class="source-lines" onclick="event.stopPropagation()"><summary>源码 (%d 行)</summary><pre>%s</pre></details>`,
+				len(node.SourceLines), template.HTMLEscapeString(strings.Join(node.SourceLines, "\n")))
+		}
 
 		if hasChildren {
-			sb.WriteString(fmt.Sprintf(`<div class="node collapsible %s">%s%s</div>`, class, nodeContent, sourceButton))
-			sb.WriteString(`<div class="children hidden">`)
+			// 分组节点显示子树规则构成
+			nodeContent += ca.ruleBarHTML(ca.aggregateRuleCounts(node))
+			if ca.html.NoJS {
+				sb.WriteString(fmt.Sprintf(`<details><summary class="node %s" %s>%s%s</summary>`, class, dataAttrs, nodeContent, sourceButton))
+				sb.WriteString(`<div class="children">`)
+			} else {
+				sb.WriteString(fmt.Sprintf(`<div class="node collapsible %s" %s>%s%s</div>`, class, dataAttrs, nodeContent, sourceButton))
+				sb.WriteString(`<div class="children hidden">`)
+			}
 		} else {
-			sb.WriteString(fmt.Sprintf(`<div class="node %s">%s%s</div>`, class, nodeContent, sourceButton))
-		}
-
-		var childNames []string
-		for name := range node.Children {
-			childNames = append(childNames, name)
+			sb.WriteString(fmt.Sprintf(`<div class="node %s" %s>%s%s</div>`, class, dataAttrs, nodeContent, sourceButton))
 		}
-		sort.Strings(childNames)
 
-		for _, childName := range childNames {
-			sb.WriteString(ca.generateHTMLTree(node.Children[childName], depth+1))
+		if hasChildren {
+			for _, childName := range sortedChildNames(node) {
+				sb.WriteString(ca.generateHTMLTree(node.Children[childName], appendPath(path, childName)))
+			}
 		}
 
 		if hasChildren {
 			sb.WriteString(`</div>`)
+			if ca.html.NoJS {
+				sb.WriteString(`</details>`)
+			}
 		}
 	} else {
-		var childNames []string
-		for name := range node.Children {
-			childNames = append(childNames, name)
+		for _, childName := range sortedChildNames(node) {
+			sb.WriteString(ca.generateHTMLTree(node.Children[childName], appendPath(path, childName)))
 		}
-		sort.Strings(childNames)
+	}
+
+	return sb.String()
+}
 
-		for _, childName := range childNames {
-			sb.WriteString(ca.generateHTMLTree(node.Children[childName], depth))
+// appendPath 返回在 path 末尾追加 name 的新切片，不影响兄弟节点共用的 path
+func appendPath(path []string, name string) []string {
+	return append(path[:len(path):len(path)], name)
+}
+
+// ruleBarHTML 生成规则类型占比的堆叠条，没有规则时返回空字符串
+func (ca *CategoryAnalyzer) ruleBarHTML(counts map[string]int) string {
+	total := 0
+	for _, ruleType := range ruleTypes {
+		total += counts[ruleType]
+	}
+	if total == 0 {
+		return ""
+	}
+
+	var segments []string
+	var tooltip []string
+	for _, ruleType := range ruleTypes {
+		count := counts[ruleType]
+		tooltip = append(tooltip, fmt.Sprintf("%s: %d", ruleType, count))
+		if count == 0 {
+			continue
 		}
+		width := float64(count) * 100 / float64(total)
+		segments = append(segments, fmt.Sprintf(`<span class="seg-%s" style="width:%.2f%%"></span>`, ruleType, width))
 	}
 
-	return sb.String()
+	return fmt.Sprintf(`<span class="rule-bar" title="%s">%s</span>`, strings.Join(tooltip, ", "), strings.Join(segments, ""))
+}
+
+// sourceURL 返回分类源文件的 raw 链接
+func (ca *CategoryAnalyzer) sourceURL(name string) string {
+	return fmt.Sprintf("%s/%s/data/%s", strings.TrimRight(ca.sourceBase, "/"), ca.sourceRef, name)
+}
+
+// checkSourceBase 检查源码链接前缀是否为带协议和主机的完整 URL
+func checkSourceBase(base string) error {
+	u, err := url.Parse(base)
+	if err != nil {
+		return fmt.Errorf("无效的 -source-base %q: %w", base, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("-source-base %q 缺少协议或主机", base)
+	}
+	return nil
+}
+
+// nodeClassColors 各节点类型的颜色，与 HTML 页面的浅色主题一致
+var nodeClassColors = map[string]string{
+	"category": "#7b1fa2",
+	"company":  "#2e7d32",
+	"geo":      "#f57c00",
+	"service":  "#1976d2",
+}
+
+// ansiReset 恢复默认终端样式
+const ansiReset = "\x1b[0m"
+
+// ansiColor 返回以 24 位真彩色显示 hex 颜色的 ANSI 转义序列
+func ansiColor(hex string, bold bool) string {
+	var r, g, b int
+	fmt.Sscanf(hex, "#%02x%02x%02x", &r, &g, &b)
+	seq := fmt.Sprintf("\x1b[38;2;%d;%d;%dm", r, g, b)
+	if bold {
+		seq = "\x1b[1m" + seq
+	}
+	return seq
+}
+
+// stdoutIsTerminal 判断标准输出是否为终端，重定向到文件或管道时不输出颜色
+func stdoutIsTerminal() bool {
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
 
 // getNodeClass 获取节点CSS类
+// 地理分类先于公司判断，避免 geolocation-* 因包含公司名而被归为公司
 func (ca *CategoryAnalyzer) getNodeClass(name string) string {
 	if strings.HasPrefix(name, "category-") {
 		return "category"
-	} else if isCompany(name) {
-		return "company"
-	} else if strings.HasPrefix(name, "geo") || isCountry(name) {
+	} else if ca.classify.isGeo(filepath.Base(name)) {
 		return "geo"
+	} else if ca.classify.isCompany(name) {
+		return "company"
 	}
 	return "service"
 }
 
+// classifyConfig 节点分类使用的公司和国家列表，可通过 -classify 文件覆盖
+type classifyConfig struct {
+	Companies []string `json:"companies"` // 名称中包含任一项即为公司
+	Countries []string `json:"countries"` // 名称与任一项完全相同即为国家
+}
+
+// defaultClassifyConfig 返回内置的公司和国家列表
+func defaultClassifyConfig() classifyConfig {
+	return classifyConfig{
+		Companies: []string{"google", "microsoft", "apple", "facebook", "amazon", "netflix", "github", "gitlab", "twitter", "youtube", "instagram", "tiktok", "zoom", "discord", "spotify", "openai", "alibaba", "baidu", "tencent", "douban", "weibo", "bilibili"},
+		Countries: []string{"cn", "us", "jp", "kr", "hk", "mo", "tw", "sg", "in", "au", "ca", "uk", "de", "fr", "ru", "ir"},
+	}
+}
+
+// loadClassifyConfig 读取 {"companies":[...],"countries":[...]} 格式的分类配置
+// 文件中缺少的列表沿用内置默认值
+func loadClassifyConfig(filename string) (classifyConfig, error) {
+	config := defaultClassifyConfig()
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return config, err
+	}
+
+	var loaded classifyConfig
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return config, fmt.Errorf("解析 %s 失败: %w", filename, err)
+	}
+	if loaded.Companies != nil {
+		config.Companies = loaded.Companies
+	}
+	if loaded.Countries != nil {
+		config.Countries = loaded.Countries
+	}
+	return config, nil
+}
+
 // 辅助函数
-func isCompany(name string) bool {
-	companies := []string{"google", "microsoft", "apple", "facebook", "amazon", "netflix", "github", "gitlab", "twitter", "youtube", "instagram", "tiktok", "zoom", "discord", "spotify", "openai", "alibaba", "baidu", "tencent", "douban", "weibo", "bilibili"}
-	for _, company := range companies {
+func (c classifyConfig) isCompany(name string) bool {
+	for _, company := range c.Companies {
 		if strings.Contains(name, company) {
 			return true
 		}
@@ -564,9 +1687,13 @@ func isCompany(name string) bool {
 	return false
 }
 
-func isCountry(name string) bool {
-	countries := []string{"cn", "us", "jp", "kr", "hk", "tw", "uk", "de", "fr", "ru"}
-	return contains(countries, name)
+func (c classifyConfig) isCountry(name string) bool {
+	return contains(c.Countries, name)
+}
+
+// isGeo 判断是否为地理分类：geolocation 及 geolocation-cn、geolocation-!cn 等变体，或国家代码
+func (c classifyConfig) isGeo(name string) bool {
+	return name == "geolocation" || strings.HasPrefix(name, "geolocation-") || c.isCountry(name)
 }
 
 func contains(slice []string, item string) bool {
@@ -578,38 +1705,137 @@ func contains(slice []string, item string) bool {
 	return false
 }
 
-func main() {
-	dataDir := "./data"
+// phaseTiming 单个阶段的耗时
+type phaseTiming struct {
+	name    string
+	elapsed time.Duration
+}
+
+// phaseProfiler 记录各阶段的墙钟耗时
+type phaseProfiler struct {
+	enabled bool
+	phases  []phaseTiming
+}
+
+// track 记录从 start 到现在的耗时
+func (p *phaseProfiler) track(name string, start time.Time) {
+	if !p.enabled {
+		return
+	}
+	p.phases = append(p.phases, phaseTiming{name: name, elapsed: time.Since(start)})
+}
+
+// Print 将各阶段耗时打印到标准错误，不混入标准输出上的导出内容
+func (p *phaseProfiler) Print() {
+	if !p.enabled || len(p.phases) == 0 {
+		return
+	}
 
-	fmt.Println("🌳 Domain List Community 多格式可视化工具")
-	fmt.Println(strings.Repeat("=", 50))
+	var total time.Duration
+	fmt.Fprintln(os.Stderr, "\n⏱️  阶段耗时:")
+	for _, phase := range p.phases {
+		fmt.Fprintf(os.Stderr, "   %-12s %v\n", phase.name, phase.elapsed.Round(time.Microsecond))
+		total += phase.elapsed
+	}
+	fmt.Fprintf(os.Stderr, "   %-12s %v\n", "合计", total.Round(time.Microsecond))
+}
 
-	analyzer := NewCategoryAnalyzer(dataDir)
+// registerPprof 在 mux 上注册 net/http/pprof 处理器
+// 出于安全考虑默认不注册，仅在显式开启 --pprof 时调用
+func registerPprof(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+}
 
-	if err := analyzer.ScanDataDirectory(); err != nil {
-		fmt.Printf("错误: %v\n", err)
-		os.Exit(1)
+// runSnapshotDiff 加载两个 JSON 快照并打印变化摘要，output 不为空时同时导出对比页面
+func runSnapshotDiff(oldFile, newFile, output string) error {
+	oldTree, err := LoadJSON(oldFile)
+	if err != nil {
+		return err
+	}
+	newTree, err := LoadJSON(newFile)
+	if err != nil {
+		return err
 	}
 
-	analyzer.BuildTree()
+	Diff(oldTree, newTree).Print()
+	if output == "" {
+		return nil
+	}
+	return ExportDiffHTML(output, DiffTrees(oldTree, newTree), oldFile, newFile)
+}
 
-	// 1. 控制台输出
-	analyzer.PrintConsoleTree()
+// runDiffCounts 分别构建两个数据目录的树，打印结构变化和各分类规则数量的变化
+func runDiffCounts(ctx context.Context, oldDir, newDir string, configure func(*CategoryAnalyzer)) error {
+	var analyzers []*CategoryAnalyzer
+	for _, dir := range []string{oldDir, newDir} {
+		analyzer := NewCategoryAnalyzer(dir)
+		configure(analyzer)
+		if err := analyzer.ScanDataDirectory(ctx); err != nil {
+			return err
+		}
+		if err := analyzer.BuildTree(ctx); err != nil {
+			return err
+		}
+		analyzers = append(analyzers, analyzer)
+	}
+	oldAnalyzer, newAnalyzer := analyzers[0], analyzers[1]
+
+	// 与 diff 对比 JSON 快照时使用同一套按分类集合的统计
+	structure := Diff(oldAnalyzer.tree, newAnalyzer.tree)
+	fmt.Printf("🌳 结构变化: 新增 %d | 删除 %d | 变化 %d\n", len(structure.Added), len(structure.Removed), len(structure.Changed))
+
+	deltas := DiffCounts(oldAnalyzer, newAnalyzer)
+	unchanged := 0
+	fmt.Printf("\n📊 规则数量变化 (%s → %s):\n", oldDir, newDir)
+	fmt.Printf("   %8s %8s %8s  %s\n", "old", "new", "delta", "category")
+	for _, d := range deltas {
+		if d.Delta == 0 {
+			unchanged++
+			continue
+		}
+		fmt.Printf("   %8d %8d %+8d  %s\n", d.Old, d.New, d.Delta, d.Name)
+	}
+	fmt.Printf("   另有 %d 个分类数量未变化\n", unchanged)
 
-	fmt.Println("\n" + strings.Repeat("=", 50))
-	fmt.Println("📤 正在生成多种格式的输出文件...")
+	return nil
+}
 
-	// 2. JSON格式
-	if err := analyzer.ExportJSON("domain_tree.json"); err != nil {
-		fmt.Printf("❌ JSON导出失败: %v\n", err)
+// version 版本号，发布时通过 -ldflags "-X main.version=v1.2.3" 注入
+var version = "dev"
+
+// versionString 返回版本号，并附上构建时嵌入的提交（有未提交修改时带 -dirty）
+func versionString() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return version
 	}
 
-	// 4. 交互式HTML
-	if err := analyzer.ExportHTML("domain_tree.html"); err != nil {
-		fmt.Printf("❌ HTML导出失败: %v\n", err)
+	var revision string
+	var modified bool
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if revision == "" {
+		return version
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
 	}
+	if modified {
+		revision += "-dirty"
+	}
+	return version + " (" + revision + ")"
+}
 
-	fmt.Println("\n✨ 完成！生成的文件:")
-	fmt.Println("   📄 domain_tree.json  - JSON数据格式")
-	fmt.Println("   🌐 domain_tree.html  - 交互式网页")
+func main() {
+	os.Exit(runCLI(os.Args[1:]))
 }
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// 构建时的警告和进度信息不混入测试输出
+	logger.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// writeFixture 在临时目录中创建 data 目录，files 的键为相对路径（可包含子目录），值为文件内容
+func writeFixture(t testing.TB, files map[string]string) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "data")
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// buildFixture 扫描 dir 并构建树
+func buildFixture(t testing.TB, dir string) *CategoryAnalyzer {
+	t.Helper()
+	ca := NewCategoryAnalyzer(dir)
+	if err := ca.ScanDataDirectory(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := ca.BuildTree(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return ca
+}
+
+// treeEdges 返回每个有子节点的分类及其排序后的子分类名
+func treeEdges(ca *CategoryAnalyzer) map[string][]string {
+	edges := make(map[string][]string)
+	for name, node := range ca.categories {
+		if len(node.Children) > 0 {
+			edges[name] = sortedChildNames(node)
+		}
+	}
+	return edges
+}
+
+func TestBuildTree(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		roots   []string
+		edges   map[string][]string
+		missing []MissingInclude
+		self    []SelfInclude
+		cycles  [][]string
+		check   func(t *testing.T, ca *CategoryAnalyzer)
+	}{
+		{
+			name:   "cycle",
+			files:  map[string]string{"a": "include:b\n", "b": "include:a\n"},
+			roots:  []string{"a"},
+			edges:  map[string][]string{"a": {"b"}},
+			cycles: [][]string{{"a", "b", "a"}},
+		},
+		{
+			name:   "attributed cycle",
+			files:  map[string]string{"a": "include:b\n", "b": "include:a @cn\n"},
+			roots:  []string{"a"},
+			edges:  map[string][]string{"a": {"b"}},
+			cycles: [][]string{{"a", "b", "a"}},
+			check: func(t *testing.T, ca *CategoryAnalyzer) {
+				if attrs, ok := ca.categories["b"].ChildAttributes["a"]; ok {
+					t.Errorf("断开的回边仍保留属性 %v", attrs)
+				}
+			},
+		},
+		{
+			name: "comment styles",
+			files: map[string]string{
+				"a": "# include:b\n// include:c\ninclude:d # 行尾注释\nexample.com // 行尾注释\nregexp:^https?://x\\.com$\n",
+				"b": "", "c": "", "d": "",
+			},
+			roots: []string{"a", "b", "c"},
+			edges: map[string][]string{"a": {"d"}},
+			check: func(t *testing.T, ca *CategoryAnalyzer) {
+				if got := ca.categories["a"].RuleCount; got != 2 {
+					t.Errorf("a 的规则数 = %d，期望 2（注释不计入，regexp 中的 // 不是注释）", got)
+				}
+			},
+		},
+		{
+			name:  "self include",
+			files: map[string]string{"a": "example.com\ninclude:a\n"},
+			roots: []string{"a"},
+			edges: map[string][]string{},
+			self:  []SelfInclude{{Category: "a", Line: 2}},
+		},
+		{
+			name:  "nested directory",
+			files: map[string]string{"category-x": "include:google\n", "sub/google": "google.com\n"},
+			roots: []string{"category-x"},
+			edges: map[string][]string{"category-x": {"sub/google"}},
+		},
+		{
+			name:  "attributed include",
+			files: map[string]string{"a": "include:geolocation-!cn @cn\n", "geolocation-!cn": "x.com @cn\ny.com\n"},
+			roots: []string{"a"},
+			edges: map[string][]string{"a": {"geolocation-!cn"}},
+			check: func(t *testing.T, ca *CategoryAnalyzer) {
+				got := ca.categories["a"].ChildAttributes["geolocation-!cn"]
+				if !reflect.DeepEqual(got, []string{"@cn"}) {
+					t.Errorf("include 属性 = %v，期望 [@cn]", got)
+				}
+			},
+		},
+		{
+			name:    "missing include",
+			files:   map[string]string{"a": "include:b\ninclude:nope\n", "b": "b.com\n"},
+			roots:   []string{"a"},
+			edges:   map[string][]string{"a": {"b"}},
+			missing: []MissingInclude{{Source: "a", Target: "nope"}},
+		},
+		{
+			name:  "uppercase include",
+			files: map[string]string{"a": "include:GOOGLE\n", "google": "google.com\n"},
+			roots: []string{"a"},
+			edges: map[string][]string{"a": {"google"}},
+		},
+		{
+			name:  "uppercase self include",
+			files: map[string]string{"google": "include:Google\ngoogle.com\n"},
+			roots: []string{"google"},
+			edges: map[string][]string{},
+			self:  []SelfInclude{{Category: "google", Line: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ca := buildFixture(t, writeFixture(t, tt.files))
+
+			if got := sortedChildNames(ca.tree); !reflect.DeepEqual(got, tt.roots) {
+				t.Errorf("顶级分类 = %v，期望 %v", got, tt.roots)
+			}
+			if got := treeEdges(ca); !reflect.DeepEqual(got, tt.edges) {
+				t.Errorf("父子关系 = %v，期望 %v", got, tt.edges)
+			}
+			if got := ca.MissingIncludes(); len(got)+len(tt.missing) > 0 && !reflect.DeepEqual(got, tt.missing) {
+				t.Errorf("MissingIncludes() = %v，期望 %v", got, tt.missing)
+			}
+			if got := ca.SelfIncludes(); len(got)+len(tt.self) > 0 && !reflect.DeepEqual(got, tt.self) {
+				t.Errorf("SelfIncludes() = %v，期望 %v", got, tt.self)
+			}
+			if got := ca.Cycles(); len(got)+len(tt.cycles) > 0 && !reflect.DeepEqual(got, tt.cycles) {
+				t.Errorf("Cycles() = %v，期望 %v", got, tt.cycles)
+			}
+			if tt.check != nil {
+				tt.check(t, ca)
+			}
+		})
+	}
+}
+
+func TestBuildTreeLongChain(t *testing.T) {
+	const n = 20000
+	files := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		if i < n-1 {
+			files[fmt.Sprintf("n%d", i)] = fmt.Sprintf("include:n%d\n", i+1)
+		} else {
+			files[fmt.Sprintf("n%d", i)] = "example.com\n"
+		}
+	}
+	ca := buildFixture(t, writeFixture(t, files))
+
+	if got := sortedChildNames(ca.tree); !reflect.DeepEqual(got, []string{"n0"}) {
+		t.Fatalf("顶级分类 = %v，期望 [n0]", got)
+	}
+	depth := 0
+	for node := ca.tree.Children["n0"]; node != nil; depth++ {
+		var next *TreeNode
+		for _, child := range node.Children {
+			next = child
+		}
+		node = next
+	}
+	if depth != n {
+		t.Errorf("链长度 = %d，期望 %d", depth, n)
+	}
+}
+
+func TestGetNodeClass(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"geolocation-cn", "geo"},
+		{"geolocation-!cn", "geo"},
+		{"cn", "geo"},
+		{"sub/cn", "geo"},
+		{"category-ads-all", "category"},
+		{"google", "company"},
+		{"google-ads", "company"},
+		{"reddit", "service"},
+	}
+
+	ca := NewCategoryAnalyzer("")
+	for _, tt := range tests {
+		if got := ca.getNodeClass(tt.name); got != tt.want {
+			t.Errorf("getNodeClass(%q) = %q，期望 %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadBogusURL(t *testing.T) {
+	for _, useGoGit := range []bool{false, true} {
+		t.Run(fmt.Sprintf("use-go-git=%v", useGoGit), func(t *testing.T) {
+			dataDir := filepath.Join(t.TempDir(), "data")
+			ca := NewCategoryAnalyzer(dataDir)
+			ca.useGoGit = useGoGit
+			ca.retries = 1
+
+			repo := filepath.Join(t.TempDir(), "no-such-repo")
+			if err := ca.DownloadV2RayRepoData(context.Background(), repo, ""); err == nil {
+				t.Fatal("克隆不存在的仓库没有返回错误")
+			}
+			if _, err := os.Stat(dataDir); !os.IsNotExist(err) {
+				t.Errorf("克隆失败后不应创建数据目录: %v", err)
+			}
+		})
+	}
+}
+
+func TestGenerateHTMLTreeAttributes(t *testing.T) {
+	dir := writeFixture(t, map[string]string{
+		"category-ads":   "include:google\ninclude:geolocation-cn\n",
+		"google":         "google.com\n",
+		"geolocation-cn": "baidu.com\n",
+	})
+	ca := buildFixture(t, dir)
+	ca.sourceBase = "https://example.com/raw?a=1&b=2"
+	html := ca.generateHTMLTree(ca.tree, nil)
+
+	for _, want := range []string{
+		`data-name="category-ads" data-depth="1" data-class="category"`,
+		`data-name="google" data-depth="2" data-class="company"`,
+		`data-name="geolocation-cn" data-depth="2" data-class="geo"`,
+		`href="https://example.com/raw?a=1&amp;b=2/refs/heads/master/data/google"`,
+		`data-url="https://example.com/raw?a=1&amp;b=2/refs/heads/master/data/google"`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML 中缺少 %s", want)
+		}
+	}
+}
+
+func TestStatsFollowsFilter(t *testing.T) {
+	ca := buildFixture(t, filepath.Join("testdata", "data"))
+	ca.tree = ca.Search("youtube")
+
+	want := TreeStats{Categories: 2, IncludeEdges: 1, MaxDepth: 2, Roots: 1}
+	if got := ca.Stats(); got != want {
+		t.Errorf("-filter youtube 后 Stats() = %+v，期望 %+v", got, want)
+	}
+}
+
+// benchmarkFiles 生成 n 个分类，每个 include 后面的两个分类并带有若干规则
+func benchmarkFiles(n int) map[string]string {
+	files := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		var b strings.Builder
+		for j := 1; j <= 2 && i+j < n; j++ {
+			fmt.Fprintf(&b, "include:c%d\n", i+j)
+		}
+		for j := 0; j < 50; j++ {
+			fmt.Fprintf(&b, "d%d-%d.example.com @cn\n", i, j)
+		}
+		files[fmt.Sprintf("c%d", i)] = b.String()
+	}
+	return files
+}
+
+func BenchmarkParse(b *testing.B) {
+	ca := NewCategoryAnalyzer(writeFixture(b, benchmarkFiles(500)))
+	if err := ca.ScanDataDirectory(context.Background()); err != nil {
+		b.Fatal(err)
+	}
+	names := make([]string, 0, len(ca.categories))
+	for name := range ca.categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	b.Run("sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, name := range names {
+				if _, err := ca.getCategoryIncludes(name); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("concurrent", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := ca.parseAll(context.Background()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// maxDepth 返回以 node 为根的子树最大深度（node 自身为 1），忽略循环
+func maxDepth(node *TreeNode) int {
+	onPath := make(map[*TreeNode]bool)
+
+	var walk func(n *TreeNode) int
+	walk = func(n *TreeNode) int {
+		if onPath[n] {
+			return 0
+		}
+		onPath[n] = true
+		defer delete(onPath, n)
+
+		deepest := 0
+		for _, child := range n.Children {
+			if d := walk(child); d > deepest {
+				deepest = d
+			}
+		}
+		return deepest + 1
+	}
+
+	return walk(node)
+}
+
+// Path 沿第一个父节点向上查找，返回从顶级分类到该节点的名称列表
+// 有多个父节点时按名称取第一个，保证输出稳定；全部路径见 Paths
+func (ca *CategoryAnalyzer) Path(node *TreeNode) []string {
+	var path []string
+	visited := make(map[*TreeNode]bool)
+	for n := node; n != nil && n != ca.tree && !visited[n]; n = firstParent(n) {
+		visited[n] = true
+		path = append(path, n.Name)
+	}
+
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// firstParent 返回节点的第一个父节点，顶级节点返回 nil
+func firstParent(node *TreeNode) *TreeNode {
+	if len(node.Parents) == 0 {
+		return nil
+	}
+	return node.Parents[0]
+}
+
+// Paths 返回从顶级分类到该节点的所有路径，节点被多个分类 include 时有多条，按字典序排序
+func (ca *CategoryAnalyzer) Paths(node *TreeNode) [][]string {
+	var paths [][]string
+	onPath := make(map[*TreeNode]bool)
+
+	// 自下而上收集，rest 为 n 之下已经走过的部分（n 在前）
+	var walk func(n *TreeNode, rest []string)
+	walk = func(n *TreeNode, rest []string) {
+		if onPath[n] {
+			return
+		}
+		onPath[n] = true
+		defer delete(onPath, n)
+
+		rest = append([]string{n.Name}, rest...)
+		if len(n.Parents) == 0 || n.Parents[0] == ca.tree {
+			paths = append(paths, rest)
+			return
+		}
+		for _, parent := range n.Parents {
+			walk(parent, rest)
+		}
+	}
+	walk(node, nil)
+
+	sort.Slice(paths, func(i, j int) bool {
+		return strings.Join(paths[i], "\x00") < strings.Join(paths[j], "\x00")
+	})
+	return paths
+}
+
+// PrintPaths 打印从顶级分类到指定分类的所有路径
+func (ca *CategoryAnalyzer) PrintPaths(name string) {
+	node, exists := ca.categories[ca.resolveInclude(name)]
+	if !exists {
+		fmt.Printf("❌ 分类不存在: %s\n", name)
+		return
+	}
+
+	paths := ca.Paths(node)
+	fmt.Printf("=== %s 的 include 路径 (%d) ===\n", node.Name, len(paths))
+	for _, path := range paths {
+		fmt.Printf("   %s\n", strings.Join(path, " > "))
+	}
+}
+
+// countDescendants 返回 node 的后代数量，共享的子节点只计一次
+func countDescendants(node *TreeNode) int {
+	visited := map[*TreeNode]bool{node: true}
+
+	var walk func(n *TreeNode)
+	walk = func(n *TreeNode) {
+		for _, child := range n.Children {
+			if !visited[child] {
+				visited[child] = true
+				walk(child)
+			}
+		}
+	}
+	walk(node)
+
+	return len(visited) - 1
+}
+
+// TopLevelDepths 返回每个顶级分类下的最大嵌套深度
+func (ca *CategoryAnalyzer) TopLevelDepths() map[string]int {
+	depths := make(map[string]int, len(ca.tree.Children))
+	for name, node := range ca.tree.Children {
+		depths[name] = maxDepth(node)
+	}
+	return depths
+}
+
+// PrintTopLevelDepths 按深度从大到小打印顶级分类的嵌套深度
+func (ca *CategoryAnalyzer) PrintTopLevelDepths() {
+	depths := ca.TopLevelDepths()
+
+	var names []string
+	for name := range depths {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if depths[names[i]] != depths[names[j]] {
+			return depths[names[i]] > depths[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	fmt.Println("=== 顶级分类嵌套深度 ===")
+	for _, name := range names {
+		fmt.Printf("   %4d  %s\n", depths[name], name)
+	}
+}
+
+// Dependents 返回 include 了 name 的分类，按名称排序
+// 基于文件中声明的 include，因此包括为断开循环而未建立的父子关系
+// name 与 include 目标一样按大小写、子目录基本名和别名解析
+func (ca *CategoryAnalyzer) Dependents(name string) []string {
+	seen := make(map[string]bool)
+	var dependents []string
+	for _, source := range ca.includedBy[ca.resolveInclude(name)] {
+		if !seen[source] {
+			seen[source] = true
+			dependents = append(dependents, source)
+		}
+	}
+	sort.Strings(dependents)
+	return dependents
+}
+
+// PrintDependents 打印 include 了 name 的分类
+func (ca *CategoryAnalyzer) PrintDependents(name string) {
+	dependents := ca.Dependents(name)
+	name = ca.resolveInclude(name)
+	if len(dependents) == 0 {
+		fmt.Printf("没有分类 include %s\n", name)
+		return
+	}
+
+	fmt.Printf("=== include %s 的分类 (%d) ===\n", name, len(dependents))
+	for _, dependent := range dependents {
+		fmt.Printf("   %s\n", dependent)
+	}
+}
+
+// Roots 返回没有被任何分类 include 的分类，按名称排序
+func (ca *CategoryAnalyzer) Roots() []string {
+	var roots []string
+	for name, node := range ca.categories {
+		if len(node.Parents) == 0 {
+			roots = append(roots, name)
+		}
+	}
+	sort.Strings(roots)
+	return roots
+}
+
+// Leaves 返回不 include 任何分类的分类，按名称排序
+func (ca *CategoryAnalyzer) Leaves() []string {
+	var leaves []string
+	for name, node := range ca.categories {
+		if len(node.Children) == 0 {
+			leaves = append(leaves, name)
+		}
+	}
+	sort.Strings(leaves)
+	return leaves
+}
+
+// TreeStats 数据集的整体统计
+type TreeStats struct {
+	Categories   int // 分类总数
+	IncludeEdges int // include 关系总数
+	MaxDepth     int // 最大嵌套深度（顶级分类为 1）
+	Roots        int // 顶级分类数量
+}
+
+// Stats 从渲染用的树（ca.tree，已应用 -root、-filter、-depth-range 等裁剪）计算整体统计
+// 被多个父节点 include 的分类只计一次，include 关系按 (父, 子) 去重
+func (ca *CategoryAnalyzer) Stats() TreeStats {
+	stats := TreeStats{Roots: len(ca.tree.Children)}
+	names := make(map[string]bool)
+	edges := make(map[[2]string]bool)
+	visited := make(map[*TreeNode]bool)
+
+	stack := make([]*TreeNode, 0, len(ca.tree.Children))
+	for _, node := range ca.tree.Children {
+		stats.MaxDepth = max(stats.MaxDepth, maxDepth(node))
+		stack = append(stack, node)
+	}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if visited[node] {
+			continue
+		}
+		visited[node] = true
+		names[node.Name] = true
+		for name, child := range node.Children {
+			edges[[2]string{node.Name, name}] = true
+			stack = append(stack, child)
+		}
+	}
+
+	stats.Categories = len(names)
+	stats.IncludeEdges = len(edges)
+	return stats
+}
+
+// PrintStats 打印一行整体统计
+func (ca *CategoryAnalyzer) PrintStats() {
+	ca.renderStats(os.Stdout)
+}
+
+// renderStats 将一行整体统计写入 w
+func (ca *CategoryAnalyzer) renderStats(w io.Writer) {
+	stats := ca.Stats()
+	fmt.Fprintf(w, "📈 分类 %d | include 关系 %d | 最大深度 %d | 顶级分类 %d\n",
+		stats.Categories, stats.IncludeEdges, stats.MaxDepth, stats.Roots)
+}
+
+// PrintShapeReport 打印根分类和叶子分类的数量及列表
+func (ca *CategoryAnalyzer) PrintShapeReport() {
+	roots, leaves := ca.Roots(), ca.Leaves()
+	fmt.Printf("=== 数据结构概况 (共 %d 个分类) ===\n", len(ca.categories))
+
+	fmt.Printf("🌱 根分类（没有被 include）: %d\n", len(roots))
+	for _, name := range roots {
+		fmt.Printf("   %s\n", name)
+	}
+	fmt.Printf("🍃 叶子分类（不 include 其他分类）: %d\n", len(leaves))
+	for _, name := range leaves {
+		fmt.Printf("   %s\n", name)
+	}
+}
+
+// nodeClassOrder 节点类型的显示顺序
+var nodeClassOrder = []string{"category", "company", "geo", "service"}
+
+// ClassCounts 按 getNodeClass 统计各类型的分类数量，被多个父节点 include 的分类只计一次
+func (ca *CategoryAnalyzer) ClassCounts() map[string]int {
+	counts := make(map[string]int, len(nodeClassOrder))
+	for _, class := range nodeClassOrder {
+		counts[class] = 0
+	}
+	for name := range ca.categories {
+		counts[ca.getNodeClass(name)]++
+	}
+	return counts
+}
+
+// classCount 单个节点类型及其分类数量
+type classCount struct {
+	Class string
+	Count int
+}
+
+// orderedClassCounts 按 nodeClassOrder 顺序返回各类型的数量
+func (ca *CategoryAnalyzer) orderedClassCounts() []classCount {
+	counts := ca.ClassCounts()
+	result := make([]classCount, len(nodeClassOrder))
+	for i, class := range nodeClassOrder {
+		result[i] = classCount{Class: class, Count: counts[class]}
+	}
+	return result
+}
+
+// PrintClassLegend 打印各类型的图例及分类数量
+func (ca *CategoryAnalyzer) PrintClassLegend() {
+	ca.renderClassLegend(os.Stdout, ca.consoleColor)
+}
+
+// renderClassLegend 将各类型的图例及分类数量写入 w
+func (ca *CategoryAnalyzer) renderClassLegend(w io.Writer, color bool) {
+	parts := make([]string, 0, len(nodeClassOrder))
+	for _, c := range ca.orderedClassCounts() {
+		label := fmt.Sprintf("■ %s %d", c.Class, c.Count)
+		if color {
+			label = ansiColor(nodeClassColors[c.Class], c.Class == "category") + label + ansiReset
+		}
+		parts = append(parts, label)
+	}
+	fmt.Fprintf(w, "📊 分类构成 (共 %d 个): %s\n", len(ca.categories), strings.Join(parts, "  "))
+}
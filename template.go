@@ -0,0 +1,654 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// defaultHTMLTemplate 内置的交互式HTML模板
+const defaultHTMLTemplate = `<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.Title}}</title>
+    <link rel="icon" href="data:image/svg+xml,%3Csvg xmlns=%22http://www.w3.org/2000/svg%22 viewBox=%220 0 100 100%22%3E%3Ctext y=%22.9em%22 font-size=%2290%22%3E%F0%9F%8C%B3%3C/text%3E%3C/svg%3E">
+    <style>
+        :root {
+            --bg-color: #f5f5f5;
+            --text-color: #333;
+            --container-bg: white;
+            --container-shadow: rgba(0,0,0,0.1);
+            --stats-bg: #e3f2fd;
+            --hover-bg: #e3f2fd;
+            --category-color: #7b1fa2;
+            --company-color: #2e7d32;
+            --geo-color: #f57c00;
+            --service-color: #1976d2;
+        }
+        body {
+            font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif;
+            margin: 20px;
+            background-color: var(--bg-color);
+            color: var(--text-color);
+            transition: background-color 0.3s ease, color 0.3s ease;
+        }
+        .container {
+            max-width: 1200px;
+            margin: 0 auto;
+            background: var(--container-bg);
+            padding: 20px;
+            border-radius: 8px;
+            box-shadow: 0 2px 10px var(--container-shadow);
+        }
+        .tree {
+            font-family: 'Courier New', monospace;
+            line-height: 1.6;
+            margin-top: 20px;
+        }
+        .node {
+            margin: 2px 0;
+            cursor: pointer;
+            transition: background-color 0.2s;
+            padding: 2px 4px;
+            display: flex;
+            align-items: center;
+            justify-content: space-between;
+        }
+        /* 按 data-depth 区分层级：顶级分类更醒目，深层节点字号略小 */
+        .node[data-depth="1"] {
+            font-size: 16px;
+            margin-top: 6px;
+        }
+        .node[data-depth="4"], .node[data-depth="5"], .node[data-depth="6"] {
+            font-size: 13px;
+        }
+        .node:hover {
+            background-color: var(--hover-bg);
+            border-radius: 4px;
+        }
+        .node-content {
+            flex: 1;
+            cursor: pointer;
+        }
+        .node.collapsible .node-content:hover {
+            text-decoration: underline;
+        }
+        .view-source-btn {
+            padding: 2px 8px;
+            background: #28a745;
+            color: white;
+            border: none;
+            border-radius: 3px;
+            font-size: 11px;
+            cursor: pointer;
+            margin-left: 10px;
+            text-decoration: none;
+            display: inline-block;
+            opacity: 0.7;
+            transition: opacity 0.2s ease;
+        }
+        .view-source-btn:hover {
+            opacity: 1;
+            background: #218838;
+        }
+        .copy-url-btn {
+            padding: 2px 8px;
+            background: #6c757d;
+            color: white;
+            border: none;
+            border-radius: 3px;
+            font-size: 11px;
+            cursor: pointer;
+            margin-left: 6px;
+            opacity: 0.7;
+            transition: opacity 0.2s ease;
+        }
+        .copy-url-btn:hover {
+            opacity: 1;
+        }
+        .source-lines {
+            display: inline-block;
+            margin-left: 6px;
+            font-size: 11px;
+            vertical-align: top;
+        }
+        .source-lines summary {
+            cursor: pointer;
+            color: #666;
+        }
+        .source-lines pre {
+            margin: 4px 0;
+            padding: 6px 8px;
+            max-height: 300px;
+            overflow: auto;
+            background: var(--stats-bg);
+            border-radius: 4px;
+            font-weight: normal;
+            color: var(--text-color);
+        }
+        .rule-badge {
+            margin-left: 8px;
+            padding: 0 6px;
+            border-radius: 8px;
+            background: #e0e0e0;
+            color: #555;
+            font-size: 11px;
+            font-weight: normal;
+        }
+        .rule-bar {
+            display: inline-flex;
+            width: 80px;
+            height: 8px;
+            margin-left: 10px;
+            border-radius: 4px;
+            overflow: hidden;
+            background: #eee;
+        }
+        .rule-bar .seg-domain { background: #1976d2; }
+        .rule-bar .seg-full { background: #2e7d32; }
+        .rule-bar .seg-keyword { background: #f57c00; }
+        .rule-bar .seg-regexp { background: #c62828; }
+        .node.context { opacity: 0.45; }
+        .truncated {
+            margin-left: 8px;
+            color: #999;
+            font-size: 12px;
+        }
+        .node.category { color: var(--category-color); font-weight: bold; }
+        .node.company { color: var(--company-color); }
+        .node.geo { color: var(--geo-color); }
+        .node.service { color: var(--service-color); }
+        .collapsible {
+            position: relative;
+        }
+        .collapsible:before {
+            content: '▼';
+            position: absolute;
+            left: -15px;
+            color: #666;
+            font-size: 10px;
+        }
+        .collapsible.collapsed:before {
+            content: '▶';
+        }
+        .children {
+            margin-left: 20px;
+        }
+        .children.hidden {
+            display: none;
+        }
+        summary.node {
+            list-style: none;
+            position: relative;
+        }
+        summary.node::-webkit-details-marker {
+            display: none;
+        }
+        summary.node:before {
+            content: '▶';
+            position: absolute;
+            left: -15px;
+            color: #666;
+            font-size: 10px;
+        }
+        details[open] > summary.node:before {
+            content: '▼';
+        }
+        .header {
+            text-align: center;
+            margin-bottom: 30px;
+        }
+        .stats {
+            background: var(--stats-bg);
+            padding: 15px;
+            border-radius: 6px;
+            margin-bottom: 20px;
+        }
+        .controls {
+            display: flex;
+            gap: 10px;
+            justify-content: center;
+            margin-bottom: 20px;
+            flex-wrap: wrap;
+        }
+        .btn {
+            padding: 10px 20px;
+            background: #007bff;
+            color: white;
+            border: none;
+            border-radius: 5px;
+            cursor: pointer;
+            font-size: 14px;
+            transition: background-color 0.2s ease;
+        }
+        .btn:hover {
+            background: #0056b3;
+        }
+        .btn:active {
+            transform: translateY(1px);
+        }
+        .level-control {
+            display: inline-flex;
+            align-items: center;
+            gap: 6px;
+            font-size: 14px;
+        }
+        .level-control input {
+            width: 50px;
+            padding: 8px;
+            border: 1px solid #ccc;
+            border-radius: 5px;
+        }
+        @media (max-width: 768px) {
+            body {
+                margin: 10px;
+            }
+            .container {
+                padding: 15px;
+            }
+            .controls {
+                flex-direction: column;
+                align-items: center;
+            }
+            .btn {
+                width: 100%;
+                max-width: 200px;
+            }
+        }
+        {{if .Options.DarkMode}}
+        body.dark {
+            --bg-color: #121212;
+            --text-color: #e0e0e0;
+            --container-bg: #1e1e1e;
+            --container-shadow: rgba(0,0,0,0.6);
+            --stats-bg: #263238;
+            --hover-bg: #263238;
+            --category-color: #ce93d8;
+            --company-color: #81c784;
+            --geo-color: #ffb74d;
+            --service-color: #64b5f6;
+        }
+        body.dark .search-box input,
+        body.dark .level-control input {
+            background: #2c2c2c;
+            color: var(--text-color);
+            border-color: #555;
+        }
+        {{end}}
+        .search-box {
+            display: flex;
+            gap: 8px;
+            justify-content: center;
+            margin-bottom: 15px;
+        }
+        .search-box input {
+            width: 100%;
+            max-width: 400px;
+            padding: 8px 12px;
+            border: 1px solid #ccc;
+            border-radius: 5px;
+            font-size: 14px;
+        }
+        .node-content mark {
+            background: #fff176;
+            color: inherit;
+            border-radius: 2px;
+        }
+        .class-filter {
+            display: flex;
+            gap: 15px;
+            justify-content: center;
+            margin-bottom: 15px;
+            font-size: 14px;
+        }
+        .stats ul {
+            display: flex;
+            gap: 30px;
+            justify-content: center;
+            list-style: none;
+            margin: 0;
+            padding: 0;
+        }
+        .stats ul.legend {
+            margin-top: 10px;
+            font-size: 14px;
+        }
+        .legend .category { color: var(--category-color); font-weight: bold; }
+        .legend .company { color: var(--company-color); }
+        .legend .geo { color: var(--geo-color); }
+        .legend .service { color: var(--service-color); }
+        .node.linked {
+            outline: 2px solid #ffb300;
+            border-radius: 4px;
+        }
+    </style>
+</head>
+<body>
+    {{if and .Options.DarkMode (not .Options.NoJS)}}
+    <script>
+        // 尽早应用主题，避免页面先以浅色闪现：优先使用保存的选择，否则跟随系统
+        (function() {
+            let theme = null;
+            try { theme = localStorage.getItem('theme'); } catch (e) {}
+            if (theme ? theme === 'dark' : window.matchMedia('(prefers-color-scheme: dark)').matches) {
+                document.body.classList.add('dark');
+            }
+        })();
+    </script>
+    {{end}}
+    <div class="container">
+        <div class="header">
+            <h1>🌳 {{.Title}}</h1>
+			<p>更新时间：{{.UpdateAt}} | 每周更新1次 | 版本 {{.Version}}</p>
+        </div>
+        
+        {{if .Options.Stats}}
+        <div class="stats">
+            <ul>
+                <li>分类总数：<strong>{{.Stats.TotalCategories}}</strong></li>
+                <li>顶级分类：<strong>{{.Stats.TopLevel}}</strong></li>
+                <li>最大深度：<strong>{{.Stats.MaxDepth}}</strong></li>
+                <li>include 关系：<strong>{{.Stats.IncludeEdges}}</strong></li>
+            </ul>
+            <ul class="legend">
+                {{range .Stats.Classes}}<li><span class="{{.Class}}">■ {{.Class}}</span>：<strong>{{.Count}}</strong></li>
+                {{end}}
+            </ul>
+        </div>
+        {{end}}
+
+        {{if not .Options.NoJS}}
+        <div class="controls">
+            <button class="btn" id="expandAllBtn">📂 展开全部</button>
+            <button class="btn" id="collapseAllBtn">📁 收起全部</button>
+            <span class="level-control">
+                显示到第 <input type="number" id="levelInput" min="1" max="{{.Stats.MaxDepth}}" value="2"> 层
+                <button class="btn" id="collapseToLevelBtn">📶 应用</button>
+                <button class="btn level-btn" data-level="1">L1</button>
+                <button class="btn level-btn" data-level="2">L2</button>
+                <button class="btn level-btn" data-level="3">L3</button>
+            </span>
+            {{if .Options.DarkMode}}<button class="btn" id="darkModeBtn">🌙 深色模式</button>{{end}}
+        </div>
+        {{if .Options.Search}}
+        <div class="search-box">
+            <input type="search" id="searchInput" placeholder="🔍 搜索分类名称...">
+            <button class="btn" id="clearSearchBtn">✖ 清除</button>
+        </div>
+        {{end}}
+        {{if .Options.ClassFilter}}
+        <div class="class-filter">
+            <label><input type="checkbox" value="category" checked> 分类</label>
+            <label><input type="checkbox" value="company" checked> 公司</label>
+            <label><input type="checkbox" value="geo" checked> 地区</label>
+            <label><input type="checkbox" value="service" checked> 服务</label>
+        </div>
+        {{end}}
+        {{end}}
+        
+        <div class="tree" id="tree">
+            {{.TreeHTML}}
+        </div>
+    </div>
+
+    {{if not .Options.NoJS}}
+    <script>
+        // 折叠/展开功能
+        document.addEventListener('click', function(e) {
+            // 如果点击的是源码按钮，不执行展开/收起逻辑
+            if (e.target.classList.contains('view-source-btn')) {
+                return;
+            }
+
+            // 复制 raw 链接到剪贴板，按钮文字短暂提示结果
+            if (e.target.classList.contains('copy-url-btn')) {
+                e.preventDefault();
+                e.stopPropagation();
+                const btn = e.target;
+                const done = text => {
+                    btn.textContent = text;
+                    setTimeout(() => { btn.textContent = '复制链接'; }, 1500);
+                };
+                if (navigator.clipboard) {
+                    navigator.clipboard.writeText(btn.dataset.url).then(() => done('已复制'), () => done('复制失败'));
+                } else {
+                    done('复制失败');
+                }
+                return;
+            }
+            
+            // 查找最近的可折叠节点
+            let targetNode = e.target;
+            
+            // 如果点击的是node-content，找到它的父节点
+            if (e.target.classList.contains('node-content')) {
+                targetNode = e.target.parentElement;
+            }
+            
+            // 如果点击的节点本身就是collapsible，或者它的父节点是collapsible
+            if (targetNode.classList.contains('collapsible')) {
+                e.preventDefault();
+                e.stopPropagation();
+                
+                targetNode.classList.toggle('collapsed');
+                const children = targetNode.nextElementSibling;
+                if (children && children.classList.contains('children')) {
+                    children.classList.toggle('hidden');
+                }
+            }
+        });
+
+        // 展开全部功能
+        document.getElementById('expandAllBtn').addEventListener('click', function() {
+            const collapsibleNodes = document.querySelectorAll('.collapsible');
+            const childrenNodes = document.querySelectorAll('.children');
+            
+            collapsibleNodes.forEach(node => {
+                node.classList.remove('collapsed');
+            });
+            
+            childrenNodes.forEach(children => {
+                children.classList.remove('hidden');
+            });
+        });
+
+        // 收起全部功能 - 只保留一级分类
+        document.getElementById('collapseAllBtn').addEventListener('click', function() {
+            // 收起所有节点
+            const allCollapsibleNodes = document.querySelectorAll('.collapsible');
+            const allChildrenNodes = document.querySelectorAll('.children');
+            
+            allCollapsibleNodes.forEach(node => {
+                node.classList.add('collapsed');
+            });
+            
+            allChildrenNodes.forEach(children => {
+                children.classList.add('hidden');
+            });
+        });
+
+        // 收起到指定层级：深度小于 N 的节点展开，其余收起
+        function collapseToLevel(level) {
+            document.querySelectorAll('.node.collapsible').forEach(node => {
+                const expand = parseInt(node.dataset.depth, 10) < level;
+                node.classList.toggle('collapsed', !expand);
+                const children = node.nextElementSibling;
+                if (children && children.classList.contains('children')) {
+                    children.classList.toggle('hidden', !expand);
+                }
+            });
+        }
+        const levelInput = document.getElementById('levelInput');
+        document.getElementById('collapseToLevelBtn').addEventListener('click', function() {
+            collapseToLevel(parseInt(levelInput.value, 10) || 1);
+        });
+        levelInput.addEventListener('keydown', function(e) {
+            if (e.key === 'Enter') {
+                collapseToLevel(parseInt(levelInput.value, 10) || 1);
+            }
+        });
+        // 快捷层级按钮同时更新输入框
+        document.querySelectorAll('.level-btn').forEach(btn => {
+            btn.addEventListener('click', function() {
+                levelInput.value = btn.dataset.level;
+                collapseToLevel(parseInt(btn.dataset.level, 10));
+            });
+        });
+
+        // 初始化：展开第一层
+        document.querySelectorAll('.tree > .node.collapsible').forEach(node => {
+            node.classList.remove('collapsed');
+            const children = node.nextElementSibling;
+            if (children) children.classList.remove('hidden');
+        });
+    </script>
+    {{if or .Options.Search .Options.ClassFilter .Options.DarkMode .Options.DeepLink}}
+    <script>
+        const nodeClasses = ['category', 'company', 'geo', 'service'];
+
+        // 展开节点的所有祖先，使其可见
+        function revealNode(node) {
+            let el = node.parentElement;
+            while (el && el.id !== 'tree') {
+                if (el.classList.contains('children')) {
+                    el.classList.remove('hidden');
+                    const owner = el.previousElementSibling;
+                    if (owner) owner.classList.remove('collapsed');
+                }
+                el = el.parentElement;
+            }
+        }
+
+        // 高亮节点名称中匹配的部分，query 为空时恢复原文
+        function highlightName(el, query) {
+            const content = el.querySelector('.node-content');
+            const name = el.dataset.name;
+            const index = query ? name.toLowerCase().indexOf(query) : -1;
+            if (index < 0) {
+                content.textContent = name;
+                return;
+            }
+            const mark = document.createElement('mark');
+            mark.textContent = name.slice(index, index + query.length);
+            content.replaceChildren(name.slice(0, index), mark, name.slice(index + query.length));
+        }
+
+        // 递归筛选节点，返回容器内是否有可见节点
+        function filterTree(container, query, classes) {
+            let anyVisible = false;
+            for (const el of container.children) {
+                if (!el.classList.contains('node')) continue;
+
+                const children = el.classList.contains('collapsible') ? el.nextElementSibling : null;
+                const childVisible = children ? filterTree(children, query, classes) : false;
+                const nodeClass = el.dataset.class;
+                const selfVisible = classes.has(nodeClass) &&
+                    (!query || el.dataset.name.toLowerCase().includes(query));
+                const visible = selfVisible || childVisible;
+                highlightName(el, query);
+
+                el.style.display = visible ? '' : 'none';
+                if (children) {
+                    children.style.display = visible ? '' : 'none';
+                    if (query && childVisible) {
+                        children.classList.remove('hidden');
+                        el.classList.remove('collapsed');
+                    }
+                }
+                anyVisible = anyVisible || visible;
+            }
+            return anyVisible;
+        }
+
+        function applyFilters() {
+            const input = document.getElementById('searchInput');
+            const query = input ? input.value.trim().toLowerCase() : '';
+            const checkboxes = document.querySelectorAll('.class-filter input');
+            const classes = new Set(checkboxes.length ? [] : nodeClasses);
+            checkboxes.forEach(cb => { if (cb.checked) classes.add(cb.value); });
+            filterTree(document.getElementById('tree'), query, classes);
+        }
+
+        {{if .Options.Search}}
+        document.getElementById('searchInput').addEventListener('input', applyFilters);
+        document.getElementById('clearSearchBtn').addEventListener('click', function() {
+            document.getElementById('searchInput').value = '';
+            applyFilters();
+        });
+        {{end}}
+        {{if .Options.ClassFilter}}
+        document.querySelectorAll('.class-filter input').forEach(cb => cb.addEventListener('change', applyFilters));
+        {{end}}
+        {{if .Options.DarkMode}}
+        // 深色模式切换，选择保存在 localStorage 中
+        function updateDarkModeBtn() {
+            document.getElementById('darkModeBtn').textContent =
+                document.body.classList.contains('dark') ? '☀️ 浅色模式' : '🌙 深色模式';
+        }
+        document.getElementById('darkModeBtn').addEventListener('click', function() {
+            const dark = document.body.classList.toggle('dark');
+            try { localStorage.setItem('theme', dark ? 'dark' : 'light'); } catch (e) {}
+            updateDarkModeBtn();
+        });
+        updateDarkModeBtn();
+        {{end}}
+        {{if .Options.DeepLink}}
+        // 根据地址栏中的 #名称 定位节点
+        function openHash() {
+            const name = decodeURIComponent(location.hash.slice(1));
+            if (!name) return;
+            const node = Array.from(document.querySelectorAll('#tree .node')).find(n => n.dataset.name === name);
+            if (!node) return;
+            document.querySelectorAll('.node.linked').forEach(n => n.classList.remove('linked'));
+            revealNode(node);
+            node.classList.add('linked');
+            node.scrollIntoView({ block: 'center' });
+        }
+
+        // 点击节点名称时更新地址栏，便于分享链接
+        document.getElementById('tree').addEventListener('click', function(e) {
+            const content = e.target.closest('.node-content');
+            if (content) {
+                history.replaceState(null, '', '#' + encodeURIComponent(content.parentElement.dataset.name));
+            }
+        });
+        window.addEventListener('hashchange', openHash);
+        openHash();
+        {{end}}
+    </script>
+    {{end}}
+    {{end}}
+</body>
+</html>`
+
+// parseHTMLTemplate 解析HTML模板，text 为空时使用内置模板
+func parseHTMLTemplate(name, text string) (*template.Template, error) {
+	if text == "" {
+		name, text = "html", defaultHTMLTemplate
+	}
+	return template.New(name).Parse(text)
+}
+
+// loadHTMLTemplate 读取并校验自定义模板
+// 除语法检查外还会用空数据试执行一次，尽早发现引用了不存在字段等错误；
+// 错误信息中包含 "模板名:行号"
+func loadHTMLTemplate(filename string) (string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("无法读取模板: %w", err)
+	}
+
+	tmpl, err := template.New(filepath.Base(filename)).Parse(string(data))
+	if err != nil {
+		return "", fmt.Errorf("模板语法错误: %w", err)
+	}
+	if err := tmpl.Execute(io.Discard, htmlTemplateData{}); err != nil {
+		return "", fmt.Errorf("模板执行失败: %w", err)
+	}
+
+	return string(data), nil
+}
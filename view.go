@@ -0,0 +1,281 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// depthRange 深度窗口，顶级分类深度为 1
+type depthRange struct {
+	Min int
+	Max int
+}
+
+// String 实现 flag.Value
+func (r *depthRange) String() string {
+	if r == nil || (r.Min == 0 && r.Max == 0) {
+		return ""
+	}
+	return fmt.Sprintf("%d-%d", r.Min, r.Max)
+}
+
+// Set 实现 flag.Value，接受 MIN-MAX 或 MIN,MAX
+func (r *depthRange) Set(value string) error {
+	sep := "-"
+	if strings.Contains(value, ",") {
+		sep = ","
+	}
+	parts := strings.SplitN(value, sep, 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("格式应为 MIN-MAX: %q", value)
+	}
+
+	min, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return fmt.Errorf("无效的最小深度: %w", err)
+	}
+	max, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return fmt.Errorf("无效的最大深度: %w", err)
+	}
+	if min < 1 || max < min {
+		return fmt.Errorf("深度范围无效: %d-%d", min, max)
+	}
+
+	r.Min, r.Max = min, max
+	return nil
+}
+
+// DepthRangeView 返回只包含指定深度范围内节点的树副本
+// 范围外但有后代落在范围内的祖先节点会被保留并标记为 Context，原树不受影响
+func (ca *CategoryAnalyzer) DepthRangeView(r depthRange) *TreeNode {
+	root := &TreeNode{Name: ca.tree.Name, Children: make(map[string]*TreeNode)}
+	onPath := make(map[*TreeNode]bool)
+
+	var copyNode func(node *TreeNode, depth int) *TreeNode
+	copyNode = func(node *TreeNode, depth int) *TreeNode {
+		if depth > r.Max || onPath[node] {
+			return nil
+		}
+		onPath[node] = true
+		defer delete(onPath, node)
+
+		view := newViewNode(node, depth < r.Min)
+		for name, child := range node.Children {
+			if childView := copyNode(child, depth+1); childView != nil {
+				childView.Parents = []*TreeNode{view}
+				view.Children[name] = childView
+			}
+		}
+
+		// 范围之上且没有可显示后代的节点直接丢弃
+		if view.Context && len(view.Children) == 0 {
+			return nil
+		}
+		return view
+	}
+
+	for name, child := range ca.tree.Children {
+		if view := copyNode(child, 1); view != nil {
+			view.Parents = []*TreeNode{root}
+			root.Children[name] = view
+		}
+	}
+
+	return root
+}
+
+// newViewNode 复制节点自身的信息（不含子节点），用于构建视图
+func newViewNode(node *TreeNode, context bool) *TreeNode {
+	return &TreeNode{
+		Name:       node.Name,
+		Children:   make(map[string]*TreeNode),
+		RuleCounts: node.RuleCounts,
+		RuleCount:  node.RuleCount,
+		RuleLines:  node.RuleLines,
+		Attributes: node.Attributes,
+		Source:     node.Source,
+
+		ChildAttributes: node.ChildAttributes,
+		Includes:        node.Includes,
+		Truncated:       node.Truncated,
+		SourceLines:     node.SourceLines,
+		Context:         context,
+	}
+}
+
+// matchName 判断分类名是否匹配搜索模式，支持子串和 filepath.Match 通配符
+func matchName(pattern, name string) bool {
+	if strings.Contains(name, pattern) {
+		return true
+	}
+	matched, _ := filepath.Match(pattern, name)
+	return matched
+}
+
+// Search 返回只包含名称匹配 pattern 的节点及其祖先的树副本
+// 不匹配的祖先标记为 Context 作为上下文保留，原树不受影响
+func (ca *CategoryAnalyzer) Search(pattern string) *TreeNode {
+	root := &TreeNode{Name: ca.tree.Name, Children: make(map[string]*TreeNode)}
+	onPath := make(map[*TreeNode]bool)
+
+	var copyNode func(node *TreeNode) *TreeNode
+	copyNode = func(node *TreeNode) *TreeNode {
+		if onPath[node] {
+			return nil
+		}
+		onPath[node] = true
+		defer delete(onPath, node)
+
+		view := newViewNode(node, !matchName(pattern, node.Name))
+		for name, child := range node.Children {
+			if childView := copyNode(child); childView != nil {
+				childView.Parents = []*TreeNode{view}
+				view.Children[name] = childView
+			}
+		}
+
+		if view.Context && len(view.Children) == 0 {
+			return nil
+		}
+		return view
+	}
+
+	for name, child := range ca.tree.Children {
+		if view := copyNode(child); view != nil {
+			view.Parents = []*TreeNode{root}
+			root.Children[name] = view
+		}
+	}
+
+	return root
+}
+
+// patternList 可重复指定的模式参数
+type patternList []string
+
+// String 实现 flag.Value
+func (p *patternList) String() string {
+	if p == nil {
+		return ""
+	}
+	return strings.Join(*p, ",")
+}
+
+// Set 实现 flag.Value，每次出现追加一个模式
+func (p *patternList) Set(value string) error {
+	if _, err := filepath.Match(value, ""); err != nil {
+		return fmt.Errorf("无效的模式 %q: %w", value, err)
+	}
+	*p = append(*p, value)
+	return nil
+}
+
+// Exclude 从已构建的树中删除名称匹配任一模式（filepath.Match）的分类，返回删除的分类数
+// 只能经由被删除分类到达的后代一并删除，仍被其他分类 include 的后代保留
+// 直接修改 ca.tree 和 ca.categories，因此对所有导出生效
+func (ca *CategoryAnalyzer) Exclude(patterns []string) int {
+	excluded := make(map[string]bool)
+	for name := range ca.categories {
+		for _, pattern := range patterns {
+			if matched, _ := filepath.Match(pattern, name); matched {
+				excluded[name] = true
+				break
+			}
+		}
+	}
+	if len(excluded) == 0 {
+		return 0
+	}
+
+	// 从保留的顶级分类出发，不经过被排除的分类，能到达的分类才保留
+	reachable := make(map[string]bool)
+	var walk func(node *TreeNode)
+	walk = func(node *TreeNode) {
+		if reachable[node.Name] || excluded[node.Name] {
+			return
+		}
+		reachable[node.Name] = true
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	for _, child := range ca.tree.Children {
+		walk(child)
+	}
+
+	removed := 0
+	for name := range ca.categories {
+		if !reachable[name] {
+			delete(ca.categories, name)
+			delete(ca.includedBy, name)
+			removed++
+		}
+	}
+	prune := func(node *TreeNode) {
+		for name := range node.Children {
+			if !reachable[name] {
+				delete(node.Children, name)
+				delete(node.ChildAttributes, name)
+			}
+		}
+	}
+	prune(ca.tree)
+	for _, node := range ca.categories {
+		prune(node)
+	}
+	for name, sources := range ca.includedBy {
+		kept := sources[:0]
+		for _, source := range sources {
+			if reachable[source] {
+				kept = append(kept, source)
+			}
+		}
+		ca.includedBy[name] = kept
+	}
+	ca.linkParents()
+
+	return removed
+}
+
+// Subtree 返回指定分类的节点，名称解析方式与 include 相同
+func (ca *CategoryAnalyzer) Subtree(name string) (*TreeNode, error) {
+	node, exists := ca.categories[ca.resolveInclude(name)]
+	if !exists {
+		return nil, fmt.Errorf("未知分类: %s", name)
+	}
+	return node, nil
+}
+
+// RootAt 只保留指定分类及其后代，并让它成为唯一的顶级分类，之后的所有导出都如同整棵树只有这一支
+func (ca *CategoryAnalyzer) RootAt(name string) error {
+	node, err := ca.Subtree(name)
+	if err != nil {
+		return err
+	}
+
+	keep := make(map[*TreeNode]bool)
+	var walk func(n *TreeNode)
+	walk = func(n *TreeNode) {
+		if keep[n] {
+			return
+		}
+		keep[n] = true
+		for _, child := range n.Children {
+			walk(child)
+		}
+	}
+	walk(node)
+
+	for categoryName, n := range ca.categories {
+		if !keep[n] {
+			delete(ca.categories, categoryName)
+		}
+	}
+	ca.linkParents()
+	// 保留原来的合成根，选中的分类作为唯一的顶级节点显示
+	ca.tree = &TreeNode{Name: ca.tree.Name, Children: map[string]*TreeNode{node.Name: node}}
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce 合并连续文件事件的等待时间，批量 checkout 只触发一次重建
+const watchDebounce = 300 * time.Millisecond
+
+// watchDataDir 监听 dir 及其子目录，文件变化平静 watchDebounce 后调用 rebuild
+// 只在监听出错时返回
+func watchDataDir(dir string, rebuild func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	if err := addWatchDirs(watcher, dir); err != nil {
+		return err
+	}
+	logger.Printf("👀 正在监听 %s 的变化（Ctrl+C 退出）\n", dir)
+
+	timer := time.NewTimer(watchDebounce)
+	timer.Stop()
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			// 忽略 .geotree_cache 和编辑器临时文件等隐藏文件
+			if strings.HasPrefix(filepath.Base(event.Name), ".") {
+				continue
+			}
+			// 新建的子目录也需要监听
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := addWatchDirs(watcher, event.Name); err != nil {
+						logger.Printf("⚠️  无法监听 %s: %v\n", event.Name, err)
+					}
+				}
+			}
+			timer.Reset(watchDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			return err
+		case <-timer.C:
+			start := time.Now()
+			rebuild()
+			logger.Printf("🔄 rebuilt in %dms\n", time.Since(start).Milliseconds())
+		}
+	}
+}
+
+// addWatchDirs 将 root 及其所有子目录加入监听，fsnotify 本身不递归
+func addWatchDirs(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		if path != root && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		return watcher.Add(path)
+	})
+}
+
+// withoutFlag 从参数列表中去掉布尔参数 name（-name、--name 及 -name=值 形式）
+func withoutFlag(args []string, name string) []string {
+	var result []string
+	for _, arg := range args {
+		trimmed := strings.TrimLeft(arg, "-")
+		if strings.HasPrefix(arg, "-") && (trimmed == name || strings.HasPrefix(trimmed, name+"=")) {
+			continue
+		}
+		result = append(result, arg)
+	}
+	return result
+}
+
+// runWatch 先生成一次，然后在数据变化时用相同参数重新生成
+// 只有第一次生成会自动下载数据；重新生成时加上 -no-download，避免 -max-age 过期刷新替换掉正在监听的目录
+// 重新生成失败（包括超过 -deadline）时只打印提示，继续监听
+func runWatch(dataDir string, args []string) int {
+	if status := runGenerate(args); status != 0 {
+		return status
+	}
+	rebuildArgs := append(append([]string(nil), args...), "-no-download")
+	rebuild := func() {
+		if status := runGenerate(rebuildArgs); status != 0 {
+			logger.Printf("⚠️  重新生成失败（退出状态 %d），继续监听\n", status)
+		}
+	}
+	if err := watchDataDir(dataDir, rebuild); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ 监听失败: %v\n", err)
+		return 1
+	}
+	return 0
+}